@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// The projects of a user are kept in the projects bucket nested in the
+// user bucket, keyed by name
+
+// SaveProject method
+func (r *TodoRepo) SaveProject(userID string, project Project) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		userBucket, err := tx.CreateBucketIfNotExists([]byte(userID))
+		if err != nil {
+			return err
+		}
+
+		projectsBucket, err := userBucket.CreateBucketIfNotExists(ProjectsKey)
+		if err != nil {
+			return err
+		}
+
+		return projectsBucket.Put([]byte(project.Name), project.data())
+	})
+}
+
+// GetProject method
+func (r *TodoRepo) GetProject(userID string, name string) (Project, error) {
+	var project Project
+
+	err := r.db.View(func(tx *bolt.Tx) error {
+		projectsBucket := projectsBucketOf(tx, userID)
+		if projectsBucket == nil {
+			return fmt.Errorf("No project found named %s", name)
+		}
+
+		data := projectsBucket.Get([]byte(name))
+		if data == nil {
+			return fmt.Errorf("No project found named %s", name)
+		}
+
+		var err error
+		project, err = makeProject(data)
+		return err
+	})
+
+	return project, err
+}
+
+// GetProjects method
+func (r *TodoRepo) GetProjects(userID string) ([]Project, error) {
+	projects := []Project{}
+
+	err := r.db.View(func(tx *bolt.Tx) error {
+		projectsBucket := projectsBucketOf(tx, userID)
+		if projectsBucket == nil {
+			return nil
+		}
+
+		return projectsBucket.ForEach(func(k []byte, v []byte) error {
+			project, err := makeProject(v)
+			if err != nil {
+				return err
+			}
+
+			projects = append(projects, project)
+			return nil
+		})
+	})
+
+	return projects, err
+}
+
+// DeleteProject method
+func (r *TodoRepo) DeleteProject(userID string, name string) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		projectsBucket := projectsBucketOf(tx, userID)
+		if projectsBucket == nil || projectsBucket.Get([]byte(name)) == nil {
+			return fmt.Errorf("No project found named %s", name)
+		}
+
+		return projectsBucket.Delete([]byte(name))
+	})
+}
+
+// GetTodosByProject method
+func (r *TodoRepo) GetTodosByProject(userID string, name string) ([]Todo, error) {
+	todos, err := r.GetAllTodos(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	inProject := []Todo{}
+	for _, todo := range todos {
+		if todo.Project == name {
+			inProject = append(inProject, todo)
+		}
+	}
+
+	return inProject, nil
+}
+
+func projectsBucketOf(tx *bolt.Tx, userID string) *bolt.Bucket {
+	userBucket := tx.Bucket([]byte(userID))
+	if userBucket == nil {
+		return nil
+	}
+
+	return userBucket.Bucket(ProjectsKey)
+}
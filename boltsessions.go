@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// The sessions of a user are kept in the sessions bucket nested in the
+// user bucket, one bucket per todo with the sessions keyed by start
+// time. The ID of the todo with the running timer is kept under the
+// running key, its session is the last one in the todo's bucket
+
+// AddSession method
+func (r *TodoRepo) AddSession(userID string, session Session) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		userBucket := tx.Bucket([]byte(userID))
+		if userBucket == nil || userBucket.Get([]byte(session.TodoID)) == nil {
+			return fmt.Errorf("No todo found for ID: %s", session.TodoID)
+		}
+
+		sessionsBucket, err := userBucket.CreateBucketIfNotExists(SessionsKey)
+		if err != nil {
+			return err
+		}
+
+		if session.running() {
+			if running := sessionsBucket.Get(RunningKey); running != nil {
+				return fmt.Errorf("A timer is already running for todo %s", running)
+			}
+
+			err = sessionsBucket.Put(RunningKey, []byte(session.TodoID))
+			if err != nil {
+				return err
+			}
+		}
+
+		todoBucket, err := sessionsBucket.CreateBucketIfNotExists([]byte(session.TodoID))
+		if err != nil {
+			return err
+		}
+
+		return todoBucket.Put(session.key(), session.data())
+	})
+}
+
+// StopSession method
+func (r *TodoRepo) StopSession(userID string, end time.Time) (Session, error) {
+	var session Session
+
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		sessionsBucket, todoBucket, key := runningSession(tx, userID)
+		if todoBucket == nil {
+			return fmt.Errorf("No timer is running")
+		}
+
+		var err error
+		session, err = makeSession(todoBucket.Get(key))
+		if err != nil {
+			return err
+		}
+		session.End = end
+
+		err = todoBucket.Put(key, session.data())
+		if err != nil {
+			return err
+		}
+
+		return sessionsBucket.Delete(RunningKey)
+	})
+
+	return session, err
+}
+
+// GetRunningSession method
+func (r *TodoRepo) GetRunningSession(userID string) (*Session, error) {
+	var session *Session
+
+	err := r.db.View(func(tx *bolt.Tx) error {
+		_, todoBucket, key := runningSession(tx, userID)
+		if todoBucket == nil {
+			return nil
+		}
+
+		s, err := makeSession(todoBucket.Get(key))
+		if err != nil {
+			return err
+		}
+		session = &s
+
+		return nil
+	})
+
+	return session, err
+}
+
+// GetSessions method
+func (r *TodoRepo) GetSessions(userID string, todoID string) ([]Session, error) {
+	sessions := []Session{}
+
+	err := r.db.View(func(tx *bolt.Tx) error {
+		todoBucket := sessionsBucketOf(tx, userID, todoID)
+		if todoBucket == nil {
+			return nil
+		}
+
+		return todoBucket.ForEach(func(k []byte, v []byte) error {
+			session, err := makeSession(v)
+			if err != nil {
+				return err
+			}
+
+			sessions = append(sessions, session)
+			return nil
+		})
+	})
+
+	return sessions, err
+}
+
+// runningSession finds the bucket and key of the running session, the
+// todo bucket is nil when no timer is running
+func runningSession(tx *bolt.Tx, userID string) (*bolt.Bucket, *bolt.Bucket, []byte) {
+	userBucket := tx.Bucket([]byte(userID))
+	if userBucket == nil {
+		return nil, nil, nil
+	}
+
+	sessionsBucket := userBucket.Bucket(SessionsKey)
+	if sessionsBucket == nil {
+		return nil, nil, nil
+	}
+
+	running := sessionsBucket.Get(RunningKey)
+	if running == nil {
+		return nil, nil, nil
+	}
+
+	todoBucket := sessionsBucket.Bucket(running)
+	if todoBucket == nil {
+		return nil, nil, nil
+	}
+
+	key, _ := todoBucket.Cursor().Last()
+	if key == nil {
+		return nil, nil, nil
+	}
+
+	return sessionsBucket, todoBucket, key
+}
+
+func sessionsBucketOf(tx *bolt.Tx, userID string, todoID string) *bolt.Bucket {
+	userBucket := tx.Bucket([]byte(userID))
+	if userBucket == nil {
+		return nil
+	}
+
+	sessionsBucket := userBucket.Bucket(SessionsKey)
+	if sessionsBucket == nil {
+		return nil
+	}
+
+	return sessionsBucket.Bucket([]byte(todoID))
+}
+
+// deleteSessions removes the sessions of a deleted todo, stopping its
+// timer if it is running
+func deleteSessions(userBucket *bolt.Bucket, todoID string) error {
+	sessionsBucket := userBucket.Bucket(SessionsKey)
+	if sessionsBucket == nil || sessionsBucket.Bucket([]byte(todoID)) == nil {
+		return nil
+	}
+
+	if string(sessionsBucket.Get(RunningKey)) == todoID {
+		err := sessionsBucket.Delete(RunningKey)
+		if err != nil {
+			return err
+		}
+	}
+
+	return sessionsBucket.DeleteBucket([]byte(todoID))
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+)
+
+// Config struct holds the user settings read from the config file
+type Config struct {
+	WeekStart time.Weekday
+	Store     string
+	Subtasks  string
+	Rollover  string
+	Context   string
+}
+
+const (
+	// SubtasksIgnore lets a todo be marked done with open subtasks
+	SubtasksIgnore = "ignore"
+	// SubtasksRequire refuses to mark a todo done with open subtasks
+	SubtasksRequire = "require"
+	// SubtasksComplete marks the open subtasks done with the todo
+	SubtasksComplete = "complete"
+)
+
+const (
+	// RolloverManual moves overdue todos to today on todo rollover only
+	RolloverManual = "manual"
+	// RolloverAuto moves overdue todos to today on every run
+	RolloverAuto = "auto"
+)
+
+var config = defaultConfig()
+
+func defaultConfig() Config {
+	return Config{
+		WeekStart: time.Monday,
+		Subtasks:  SubtasksIgnore,
+		Rollover:  RolloverManual,
+	}
+}
+
+// loadConfig reads the config file at path. The file has one
+// "key = value" setting per line, blank lines and lines starting
+// with # are ignored. A missing file gives the default config
+func loadConfig(path string) (Config, error) {
+	cfg := defaultConfig()
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, err
+	}
+
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			return cfg, fmt.Errorf("Invalid config at line %d: %s", i+1, line)
+		}
+
+		err = cfg.set(strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]))
+		if err != nil {
+			return cfg, err
+		}
+	}
+
+	return cfg, nil
+}
+
+func (c *Config) set(key string, value string) error {
+	switch key {
+	case "weekstart":
+		switch strings.ToLower(value) {
+		case "monday", "mon":
+			c.WeekStart = time.Monday
+		case "sunday", "sun":
+			c.WeekStart = time.Sunday
+		default:
+			return fmt.Errorf("Unknown value for weekstart: %s", value)
+		}
+	case "db":
+		c.Store = value
+	case "subtasks":
+		switch value {
+		case SubtasksIgnore, SubtasksRequire, SubtasksComplete:
+			c.Subtasks = value
+		default:
+			return fmt.Errorf("Unknown value for subtasks: %s", value)
+		}
+	case "context":
+		context := strings.TrimPrefix(value, "@")
+		if !contextNameRe.MatchString(context) {
+			return fmt.Errorf("Invalid value for context: %s", value)
+		}
+		c.Context = context
+	case "rollover":
+		switch value {
+		case RolloverManual, RolloverAuto:
+			c.Rollover = value
+		default:
+			return fmt.Errorf("Unknown value for rollover: %s", value)
+		}
+	default:
+		return fmt.Errorf("Unknown config key: %s", key)
+	}
+
+	return nil
+}
@@ -9,6 +9,30 @@ var PendingKey = []byte("pending")
 // ListingKey key
 var ListingKey = []byte("listing")
 
+// MetaKey key of the bucket holding database wide metadata
+var MetaKey = []byte("meta")
+
+// SessionsKey key of the bucket holding the work sessions of a user
+var SessionsKey = []byte("sessions")
+
+// TagsKey key of the tag index bucket of a user, holding a bucket of
+// todo IDs per tag
+var TagsKey = []byte("tags")
+
+// SearchKey key of the search index bucket of a user, holding a bucket
+// of todo IDs per word of the titles and notes
+var SearchKey = []byte("search")
+
+// ProjectsKey key of the bucket holding the projects of a user
+var ProjectsKey = []byte("projects")
+
+// RunningKey key of the ID of the todo with the running timer, kept in
+// the sessions bucket
+var RunningKey = []byte("running")
+
+// VersionKey key of the schema version in the meta bucket
+var VersionKey = []byte("version")
+
 // OpType type
 type OpType string
 
@@ -27,16 +51,52 @@ const (
 	SetDue = "setDue"
 	// SetTags option
 	SetTags = "setTags"
-	// SetEffort option
-	SetEffort = "setEffort"
+	// SetEstimate option
+	SetEstimate = "setEstimate"
 	// DeleteTodo option
 	DeleteTodo = "delete"
 	// UpdateTodo option
 	UpdateTodo = "update"
+	// EditTodo option
+	EditTodo = "edit"
+	// AddNote option
+	AddNote = "note"
+	// AddSubtask option
+	AddSubtask = "addSubtask"
+	// SetSubtaskDone option
+	SetSubtaskDone = "setSubtaskDone"
+	// DeleteSubtask option
+	DeleteSubtask = "deleteSubtask"
+	// EditSeries option
+	EditSeries = "series"
+	// MigrateStore option
+	MigrateStore = "migrate"
+	// CheckStore option
+	CheckStore = "fsck"
+	// RolloverTodos option
+	RolloverTodos = "rollover"
+	// StartTimer option
+	StartTimer = "start"
+	// StopTimer option
+	StopTimer = "stop"
+	// ShowTags option
+	ShowTags = "tags"
+	// ChangeTags option
+	ChangeTags = "changeTags"
+	// RenameTag option
+	RenameTag = "renameTag"
+	// SetContext option
+	SetContext = "context"
+	// ListProjects option
+	ListProjects = "projects"
+	// ShowProject option
+	ShowProject = "project"
+	// UpdateProject option
+	UpdateProject = "updateProject"
 )
 
 // Operation type
-type Operation func(Opts, *TodoRepo) error
+type Operation func(Opts, Repository) error
 
 // OperationMap mapping
 var OperationMap = map[OpType]Operation{
@@ -46,7 +106,24 @@ var OperationMap = map[OpType]Operation{
 	SetDone:        setDone,
 	SetDue:         setDue,
 	SetTags:        setTags,
-	SetEffort:      setEffort,
+	SetEstimate:    setEstimate,
 	DeleteTodo:     deleteTodo,
 	UpdateTodo:     updateTodo,
+	EditTodo:       editTodo,
+	AddNote:        addNote,
+	AddSubtask:     addSubtask,
+	SetSubtaskDone: setSubtaskDone,
+	DeleteSubtask:  deleteSubtask,
+	EditSeries:     editSeries,
+	CheckStore:     checkStore,
+	RolloverTodos:  rolloverTodos,
+	StartTimer:     startTimer,
+	StopTimer:      stopTimer,
+	SetContext:     setContext,
+	ShowTags:       showTags,
+	ChangeTags:     changeTags,
+	RenameTag:      renameTag,
+	ListProjects:   listProjects,
+	ShowProject:    showProject,
+	UpdateProject:  updateProject,
 }
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var contextNameRe = regexp.MustCompile(`^[a-zA-Z][\w-]*$`)
+
+// isContexts reads @office or @office,@phone as the places the todo can
+// be done at, @none clears them
+func isContexts(param string) (bool, []string) {
+	if !strings.HasPrefix(param, "@") {
+		return false, nil
+	}
+
+	contexts := []string{}
+	for _, context := range strings.Split(param, ",") {
+		context = strings.TrimPrefix(context, "@")
+		if !contextNameRe.MatchString(context) {
+			return false, nil
+		}
+		if context != "none" {
+			contexts = append(contexts, context)
+		}
+	}
+
+	return true, contexts
+}
+
+// inContext tells if the todo is relevant in the context, todos without
+// contexts can be done anywhere
+func (t Todo) inContext(context string) bool {
+	if context == "" || len(t.Contexts) == 0 {
+		return true
+	}
+
+	for _, c := range t.Contexts {
+		if c == context {
+			return true
+		}
+	}
+	return false
+}
+
+func (t Todo) contextsstr() string {
+	if len(t.Contexts) == 0 {
+		return ""
+	}
+	return "@" + strings.Join(t.Contexts, " @")
+}
+
+// setContext shows or changes the current context. It is kept in the
+// config file so that it holds until it is changed again
+func setContext(opts Opts, repo Repository) error {
+	contexti, present := opts.params["context"]
+	if !present {
+		if config.Context == "" {
+			fmt.Println("No context set")
+		} else {
+			fmt.Printf("Current context is @%s\n", config.Context)
+		}
+		return nil
+	}
+
+	context := contexti.(string)
+	err := saveConfigValue(getConfigPath(), "context", context)
+	if err != nil {
+		return err
+	}
+
+	if context == "" {
+		fmt.Println("Context cleared")
+	} else {
+		fmt.Printf("Context set to @%s\n", context)
+	}
+	return nil
+}
+
+// saveConfigValue sets key in the config file at path, replacing the
+// line it is on and leaving the rest of the file as it is. An empty
+// value removes the key
+func saveConfigValue(path string, key string, value string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	lines := []string{}
+	if len(data) > 0 {
+		lines = strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	}
+
+	kept := []string{}
+	for _, line := range lines {
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) == 2 && strings.TrimSpace(parts[0]) == key {
+			continue
+		}
+		kept = append(kept, line)
+	}
+
+	if value != "" {
+		kept = append(kept, key+" = "+value)
+	}
+
+	content := ""
+	if len(kept) > 0 {
+		content = strings.Join(kept, "\n") + "\n"
+	}
+	return ioutil.WriteFile(path, []byte(content), 0600)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestIsContexts(t *testing.T) {
+	tests := []struct {
+		param    string
+		ok       bool
+		contexts []string
+	}{
+		{"@office", true, []string{"office"}},
+		{"@office,@phone", true, []string{"office", "phone"}},
+		{"@office,phone", true, []string{"office", "phone"}},
+		{"@none", true, []string{}},
+		{"office", false, nil},
+		{"@", false, nil},
+		{"@2nd", false, nil},
+	}
+
+	for _, test := range tests {
+		ok, contexts := isContexts(test.param)
+		if ok != test.ok || !reflect.DeepEqual(contexts, test.contexts) {
+			t.Errorf("isContexts(%q) = %v, %v, want %v, %v", test.param, ok, contexts, test.ok, test.contexts)
+		}
+	}
+}
+
+func TestInContext(t *testing.T) {
+	anywhere := newTestTodo("anywhere", today())
+	calls := newTestTodo("calls", today())
+	calls.Contexts = []string{"phone", "office"}
+
+	tests := []struct {
+		todo    Todo
+		context string
+		want    bool
+	}{
+		{anywhere, "", true},
+		{anywhere, "office", true},
+		{calls, "", true},
+		{calls, "phone", true},
+		{calls, "home", false},
+	}
+
+	for _, test := range tests {
+		if got := test.todo.inContext(test.context); got != test.want {
+			t.Errorf("%s inContext(%q) = %v, want %v", test.todo.Title, test.context, got, test.want)
+		}
+	}
+}
+
+func TestSaveConfigValue(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	err := ioutil.WriteFile(path, []byte("# settings\nweekstart = sun\ncontext = home\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		value string
+		want  string
+	}{
+		{"office", "# settings\nweekstart = sun\ncontext = office\n"},
+		{"", "# settings\nweekstart = sun\n"},
+	}
+
+	for _, step := range steps {
+		err = saveConfigValue(path, "context", step.value)
+		if err != nil {
+			t.Fatalf("saveConfigValue(context, %q): %v", step.value, err)
+		}
+
+		data, _ := ioutil.ReadFile(path)
+		if string(data) != step.want {
+			t.Errorf("config after setting context %q = %q, want %q", step.value, data, step.want)
+		}
+	}
+}
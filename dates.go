@@ -0,0 +1,251 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tues": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+var relativeDateRe = regexp.MustCompile(`^([+-])(\d+)([dwmy])$`)
+
+var inDateRe = regexp.MustCompile(`^in (\d+) (day|week|month|year)s?$`)
+
+var clockRe = regexp.MustCompile(`(?:^|\s)(\d{1,2}):(\d\d)$`)
+
+var meridiemRe = regexp.MustCompile(`(?:^|\s)(\d{1,2})(?::(\d\d))?\s?(am|pm)$`)
+
+// toDate converts a date given by the user to the start of that day in
+// the local zone. Besides YYYY-MM-DD it understands today, tomorrow,
+// yesterday, week days (fri, next monday, last tue), offsets (+3d, -1w,
+// in 2 weeks) and period boundaries (eow, end of month, start of year).
+// A trailing time of day, like "tomorrow 15:00" or "fri 3pm", gives
+// that time instead of the start of the day
+func toDate(str string) (time.Time, error) {
+	str = strings.ToLower(strings.Join(strings.Fields(str), " "))
+
+	rest, hour, minute, hasClock := splitClock(str)
+	if !hasClock {
+		return toDay(str)
+	}
+
+	if rest == "" {
+		rest = "today"
+	}
+	day, err := toDay(rest)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	year, month, date := day.Date()
+	return time.Date(year, month, date, hour, minute, 0, 0, day.Location()), nil
+}
+
+// splitClock takes a trailing time of day like 15:00, 9:30am or 3pm off
+// str and returns the rest of str with the hour and minute
+func splitClock(str string) (string, int, int, bool) {
+	if match := clockRe.FindStringSubmatch(str); match != nil {
+		hour, _ := strconv.Atoi(match[1])
+		minute, _ := strconv.Atoi(match[2])
+		if hour < 24 && minute < 60 {
+			return strings.TrimSpace(str[:len(str)-len(match[0])]), hour, minute, true
+		}
+	}
+
+	if match := meridiemRe.FindStringSubmatch(str); match != nil {
+		hour, _ := strconv.Atoi(match[1])
+		minute, _ := strconv.Atoi(match[2])
+		if hour >= 1 && hour <= 12 && minute < 60 {
+			hour = hour % 12
+			if match[3] == "pm" {
+				hour += 12
+			}
+			return strings.TrimSpace(str[:len(str)-len(match[0])]), hour, minute, true
+		}
+	}
+
+	return str, 0, 0, false
+}
+
+// toDay reads the date part of what toDate is given
+func toDay(str string) (time.Time, error) {
+	now := today()
+	year, month, _ := now.Date()
+
+	switch str {
+	case "today":
+		return now, nil
+	case "tomorrow":
+		return now.AddDate(0, 0, 1), nil
+	case "yesterday":
+		return now.AddDate(0, 0, -1), nil
+	case "sow", "start of week":
+		return startOfWeek(now), nil
+	case "eow", "end of week":
+		return startOfWeek(now).AddDate(0, 0, 6), nil
+	case "som", "start of month":
+		return time.Date(year, month, 1, 0, 0, 0, 0, now.Location()), nil
+	case "eom", "end of month":
+		return time.Date(year, month+1, 0, 0, 0, 0, 0, now.Location()), nil
+	case "soy", "start of year":
+		return time.Date(year, time.January, 1, 0, 0, 0, 0, now.Location()), nil
+	case "eoy", "end of year":
+		return time.Date(year, time.December, 31, 0, 0, 0, 0, now.Location()), nil
+	case "next week":
+		return startOfWeek(now).AddDate(0, 0, 7), nil
+	case "next month":
+		return time.Date(year, month+1, 1, 0, 0, 0, 0, now.Location()), nil
+	case "next year":
+		return time.Date(year+1, time.January, 1, 0, 0, 0, 0, now.Location()), nil
+	}
+
+	if weekday, ok := weekdays[str]; ok {
+		return nextWeekday(now, weekday, false), nil
+	}
+
+	if weekday, ok := weekdays[strings.TrimPrefix(str, "next ")]; ok && strings.HasPrefix(str, "next ") {
+		return nextWeekday(now, weekday, true), nil
+	}
+
+	if weekday, ok := weekdays[strings.TrimPrefix(str, "last ")]; ok && strings.HasPrefix(str, "last ") {
+		return lastWeekday(now, weekday), nil
+	}
+
+	if match := relativeDateRe.FindStringSubmatch(str); match != nil {
+		n, _ := strconv.Atoi(match[2])
+		if match[1] == "-" {
+			n = -n
+		}
+		return addPeriod(now, n, match[3]), nil
+	}
+
+	if match := inDateRe.FindStringSubmatch(str); match != nil {
+		n, _ := strconv.Atoi(match[1])
+		return addPeriod(now, n, match[2][:1]), nil
+	}
+
+	if regexp.MustCompile(`^\d\d\d\d-\d\d-\d\d$`).MatchString(str) {
+		parsed, err := time.ParseInLocation("2006-01-02", str, time.Local)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("Invalid date %s: %v", str, err)
+		}
+		return parsed, nil
+	}
+
+	return time.Time{}, fmt.Errorf("Unknown date: %s", str)
+}
+
+// nextWeekday returns the first given weekday on or after from. When
+// strict is set from itself is never returned
+func nextWeekday(from time.Time, weekday time.Weekday, strict bool) time.Time {
+	days := (int(weekday) - int(from.Weekday()) + 7) % 7
+	if days == 0 && strict {
+		days = 7
+	}
+	return from.AddDate(0, 0, days)
+}
+
+// lastWeekday returns the most recent given weekday before from
+func lastWeekday(from time.Time, weekday time.Weekday) time.Time {
+	days := (int(from.Weekday()) - int(weekday) + 7) % 7
+	if days == 0 {
+		days = 7
+	}
+	return from.AddDate(0, 0, -days)
+}
+
+func addPeriod(from time.Time, n int, unit string) time.Time {
+	switch unit {
+	case "w":
+		return from.AddDate(0, 0, 7*n)
+	case "m":
+		return from.AddDate(0, n, 0)
+	case "y":
+		return from.AddDate(n, 0, 0)
+	default:
+		return from.AddDate(0, 0, n)
+	}
+}
+
+// withClock returns day at the time of day of clock
+func withClock(day time.Time, clock time.Time) time.Time {
+	year, month, date := day.Date()
+	return time.Date(year, month, date, clock.Hour(), clock.Minute(), 0, 0, day.Location())
+}
+
+// zoneName returns the name under which the zone of t is stored with a
+// due date: the IANA name when it is known, otherwise the fixed offset
+// like UTC+05:30
+func zoneName(t time.Time) string {
+	loc := t.Location()
+	if loc == time.Local {
+		if name := localZoneName(); name != "" {
+			return name
+		}
+	} else if name := loc.String(); name != "" && name != "Local" {
+		if _, err := time.LoadLocation(name); err == nil {
+			return name
+		}
+	}
+
+	_, offset := t.Zone()
+	sign := "+"
+	if offset < 0 {
+		sign, offset = "-", -offset
+	}
+	return fmt.Sprintf("UTC%s%02d:%02d", sign, offset/3600, offset%3600/60)
+}
+
+// zoneLocation loads a zone stored by zoneName
+func zoneLocation(name string) (*time.Location, error) {
+	if match := regexp.MustCompile(`^UTC([+-])(\d\d):(\d\d)$`).FindStringSubmatch(name); match != nil {
+		hours, _ := strconv.Atoi(match[2])
+		minutes, _ := strconv.Atoi(match[3])
+		offset := hours*3600 + minutes*60
+		if match[1] == "-" {
+			offset = -offset
+		}
+		return time.FixedZone(name, offset), nil
+	}
+
+	return time.LoadLocation(name)
+}
+
+// localZoneName returns the IANA name of the machine's zone, from TZ or
+// the /etc/localtime link, or "" when it can't be told
+func localZoneName() string {
+	if tz := strings.TrimPrefix(os.Getenv("TZ"), ":"); tz != "" {
+		if _, err := time.LoadLocation(tz); err == nil {
+			return tz
+		}
+		return ""
+	}
+
+	link, err := os.Readlink("/etc/localtime")
+	if err != nil {
+		return ""
+	}
+
+	i := strings.Index(link, "zoneinfo/")
+	if i < 0 {
+		return ""
+	}
+
+	name := link[i+len("zoneinfo/"):]
+	if _, err := time.LoadLocation(name); err != nil {
+		return ""
+	}
+	return name
+}
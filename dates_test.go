@@ -0,0 +1,120 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestToDate(t *testing.T) {
+	now := today()
+	year, month, _ := now.Date()
+
+	tests := []struct {
+		input string
+		want  time.Time
+	}{
+		{"today", now},
+		{"Tomorrow", now.AddDate(0, 0, 1)},
+		{"yesterday", now.AddDate(0, 0, -1)},
+		{"2026-10-01", time.Date(2026, time.October, 1, 0, 0, 0, 0, time.Local)},
+		{"+3d", now.AddDate(0, 0, 3)},
+		{"-1w", now.AddDate(0, 0, -7)},
+		{"in 2 weeks", now.AddDate(0, 0, 14)},
+		{"end of month", time.Date(year, month+1, 0, 0, 0, 0, 0, time.Local)},
+		{"eow", startOfWeek(now).AddDate(0, 0, 6)},
+	}
+
+	for _, test := range tests {
+		got, err := toDate(test.input)
+		if err != nil {
+			t.Errorf("toDate(%q) returned error: %v", test.input, err)
+			continue
+		}
+		if !got.Equal(test.want) {
+			t.Errorf("toDate(%q) = %v, want %v", test.input, got, test.want)
+		}
+	}
+}
+
+func TestToDateWeekdays(t *testing.T) {
+	tests := []struct {
+		input   string
+		weekday time.Weekday
+		min     int
+		max     int
+	}{
+		{"fri", time.Friday, 0, 6},
+		{"monday", time.Monday, 0, 6},
+		{"next tue", time.Tuesday, 1, 7},
+		{"last sun", time.Sunday, -7, -1},
+	}
+
+	for _, test := range tests {
+		got, err := toDate(test.input)
+		if err != nil {
+			t.Errorf("toDate(%q) returned error: %v", test.input, err)
+			continue
+		}
+
+		days := int(math.Round(got.Sub(today()).Hours() / 24))
+		if got.Weekday() != test.weekday || days < test.min || days > test.max {
+			t.Errorf("toDate(%q) = %v, want a %v %d to %d days ahead", test.input, got, test.weekday, test.min, test.max)
+		}
+	}
+}
+
+func TestNextWeekday(t *testing.T) {
+	friday := time.Date(2026, time.October, 16, 0, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		weekday time.Weekday
+		strict  bool
+		want    int
+	}{
+		{time.Friday, false, 0},
+		{time.Friday, true, 7},
+		{time.Saturday, false, 1},
+		{time.Thursday, true, 6},
+	}
+
+	for _, test := range tests {
+		want := friday.AddDate(0, 0, test.want)
+		if got := nextWeekday(friday, test.weekday, test.strict); !got.Equal(want) {
+			t.Errorf("nextWeekday(%v, %v, %v) = %v, want %v", friday, test.weekday, test.strict, got, want)
+		}
+	}
+
+	if got, want := lastWeekday(friday, time.Friday), friday.AddDate(0, 0, -7); !got.Equal(want) {
+		t.Errorf("lastWeekday(%v, Friday) = %v, want %v", friday, got, want)
+	}
+}
+
+func TestToDateInvalid(t *testing.T) {
+	for _, input := range []string{"", "someday", "2026-13-01", "+3x", "in two weeks"} {
+		if got, err := toDate(input); err == nil {
+			t.Errorf("toDate(%q) = %v, want an error", input, got)
+		}
+	}
+}
+
+func TestIsDate(t *testing.T) {
+	tests := []struct {
+		param string
+		date  bool
+	}{
+		{"today", true},
+		{"2026-02-28", true},
+		{"next friday", true},
+		{"#work", false},
+		{"2.5", false},
+		{"!high", false},
+		{"laundry", false},
+	}
+
+	for _, test := range tests {
+		if got, _ := isDate(test.param); got != test.date {
+			t.Errorf("isDate(%q) = %v, want %v", test.param, got, test.date)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// resolveDependencies turns the todos given by list index or by ID into
+// todo IDs
+func resolveDependencies(refs []string, repo Repository) ([]string, error) {
+	listMapping := repo.GetListMapping()
+	ids := []string{}
+
+	for _, ref := range refs {
+		id, ok := listMapping[ref]
+		if !ok {
+			id = ref
+		}
+
+		if _, err := repo.GetTodo(UserKey, id); err != nil {
+			return nil, fmt.Errorf("Unknown todo %s in dependencies", ref)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// checkDependencies refuses dependencies for todoID that lead back to
+// it, directly or through the dependencies of the dependencies
+func checkDependencies(todoID string, dependsOn []string, repo Repository) error {
+	visited := map[string]bool{}
+
+	var visit func(id string) error
+	visit = func(id string) error {
+		if id == todoID {
+			return fmt.Errorf("Dependency cycle, the todo would end up depending on itself")
+		}
+		if visited[id] {
+			return nil
+		}
+		visited[id] = true
+
+		todo, err := repo.GetTodo(UserKey, id)
+		if err != nil {
+			// Dependencies on deleted todos no longer block anything
+			return nil
+		}
+
+		for _, next := range todo.DependsOn {
+			err = visit(next)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	for _, id := range dependsOn {
+		err := visit(id)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// openBlockers returns the todos the todo depends on that are not done
+func openBlockers(todo Todo, repo Repository) []Todo {
+	blockers := []Todo{}
+	for _, id := range todo.DependsOn {
+		dependency, err := repo.GetTodo(UserKey, id)
+		if err == nil && !dependency.Done {
+			blockers = append(blockers, dependency)
+		}
+	}
+
+	return blockers
+}
+
+// blockedTodos returns the IDs of the todos having open blockers
+func blockedTodos(todos []Todo, repo Repository) map[string]bool {
+	blocked := map[string]bool{}
+	for _, todo := range todos {
+		if len(openBlockers(todo, repo)) > 0 {
+			blocked[todo.ID] = true
+		}
+	}
+
+	return blocked
+}
+
+func titles(todos []Todo) string {
+	names := []string{}
+	for _, todo := range todos {
+		names = append(names, todo.Title)
+	}
+
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,51 @@
+package main
+
+import "testing"
+
+func TestCheckDependencies(t *testing.T) {
+	repo := newMemoryRepo()
+	a := newTestTodo("a", today())
+	b := newTestTodo("b", today())
+	b.DependsOn = []string{a.ID}
+	c := newTestTodo("c", today())
+	c.DependsOn = []string{b.ID, "deleted"}
+	createTodos(t, repo, a, b, c)
+
+	tests := []struct {
+		name      string
+		todoID    string
+		dependsOn []string
+		cycle     bool
+	}{
+		{"on itself", a.ID, []string{a.ID}, true},
+		{"direct cycle", a.ID, []string{b.ID}, true},
+		{"cycle through two todos", a.ID, []string{c.ID}, true},
+		{"on a dependency of a dependency", c.ID, []string{a.ID}, false},
+		{"on a todo depending on it", b.ID, []string{c.ID, "deleted"}, true},
+		{"on a deleted todo", a.ID, []string{"deleted"}, false},
+	}
+
+	for _, test := range tests {
+		err := checkDependencies(test.todoID, test.dependsOn, repo)
+		if (err != nil) != test.cycle {
+			t.Errorf("checkDependencies %s returned %v, want a cycle %v", test.name, err, test.cycle)
+		}
+	}
+}
+
+func TestBlockedTodos(t *testing.T) {
+	repo := newMemoryRepo()
+	a := newTestTodo("a", today())
+	done := newTestTodo("done", today())
+	done.Done = true
+	b := newTestTodo("b", today())
+	b.DependsOn = []string{a.ID}
+	c := newTestTodo("c", today())
+	c.DependsOn = []string{done.ID, "deleted"}
+	createTodos(t, repo, a, done, b, c)
+
+	blocked := blockedTodos([]Todo{a, b, c}, repo)
+	if len(blocked) != 1 || !blocked[b.ID] {
+		t.Errorf("blockedTodos = %v, want only b", blocked)
+	}
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// editableTodo is the form of a todo shown in the editor by todo <n> edit
+type editableTodo struct {
+	Title    string         `yaml:"title"`
+	Due      string         `yaml:"due"`
+	Done     bool           `yaml:"done"`
+	Estimate float32        `yaml:"estimate"`
+	Tags     []string       `yaml:"tags,flow"`
+	Project  string         `yaml:"project"`
+	Contexts []string       `yaml:"contexts,flow"`
+	Priority string         `yaml:"priority"`
+	Recur    string         `yaml:"recur"`
+	Subtasks []Subtask      `yaml:"subtasks,omitempty"`
+	Notes    []editableNote `yaml:"notes,omitempty"`
+}
+
+type editableNote struct {
+	Time string `yaml:"time"`
+	Text string `yaml:"text"`
+}
+
+const noteTimeFormat = "2006-01-02 15:04"
+
+func editDueFormat(todo Todo) string {
+	if todo.hasTime() {
+		return "2006-01-02 15:04"
+	}
+	return "2006-01-02"
+}
+
+func toEditable(todo Todo) editableTodo {
+	e := editableTodo{
+		Title:    todo.Title,
+		Due:      todo.dueLocal().Format(editDueFormat(todo)),
+		Done:     todo.Done,
+		Estimate: todo.Estimate,
+		Tags:     todo.Tags,
+		Project:  todo.Project,
+		Contexts: todo.Contexts,
+		Priority: strings.ToLower(todo.Priority.String()),
+		Recur:    todo.Recur,
+		Subtasks: todo.Subtasks,
+	}
+
+	for _, note := range todo.Notes {
+		e.Notes = append(e.Notes, editableNote{
+			Time: note.Time.Format(noteTimeFormat),
+			Text: note.Text,
+		})
+	}
+
+	return e
+}
+
+// applyEdit sets on todo the fields that differ between the todo as it
+// was opened in the editor and as it was saved, other fields are left
+// as they are in the store. Marking a recurring todo done returns its
+// next occurrence
+func applyEdit(todo *Todo, opened editableTodo, saved editableTodo) (*Todo, error) {
+	if saved.Title != opened.Title {
+		if strings.TrimSpace(saved.Title) == "" {
+			return nil, fmt.Errorf("Title can not be empty")
+		}
+		todo.Title = saved.Title
+	}
+
+	if saved.Due != opened.Due {
+		due, err := toDate(saved.Due)
+		if err != nil {
+			return nil, err
+		}
+		todo.Due = due
+	}
+
+	if !subtasksEqual(saved.Subtasks, opened.Subtasks) {
+		subtasks := []Subtask{}
+		for _, subtask := range saved.Subtasks {
+			if strings.TrimSpace(subtask.Title) != "" {
+				subtasks = append(subtasks, subtask)
+			}
+		}
+		todo.Subtasks = subtasks
+	}
+
+	if saved.Estimate != opened.Estimate {
+		todo.Estimate = saved.Estimate
+	}
+
+	if saved.Project != opened.Project {
+		if saved.Project != "" && !projectNameRe.MatchString(saved.Project) {
+			return nil, fmt.Errorf("Invalid project name %s, expected a word starting with a letter", saved.Project)
+		}
+		todo.Project = saved.Project
+	}
+
+	if strings.Join(saved.Contexts, ",") != strings.Join(opened.Contexts, ",") {
+		contexts := []string{}
+		for _, context := range saved.Contexts {
+			context = strings.TrimPrefix(context, "@")
+			if !contextNameRe.MatchString(context) {
+				return nil, fmt.Errorf("Invalid context %s, expected a name like office", context)
+			}
+			contexts = append(contexts, context)
+		}
+		todo.Contexts = contexts
+	}
+
+	if saved.Priority != opened.Priority {
+		priority, err := toPriority(saved.Priority)
+		if err != nil {
+			return nil, err
+		}
+		todo.Priority = priority
+	}
+
+	if !notesEqual(saved.Notes, opened.Notes) {
+		notes := []Note{}
+		for _, note := range saved.Notes {
+			text := strings.TrimSpace(note.Text)
+			if text == "" {
+				continue
+			}
+
+			// A note added in the editor without a time is stamped now
+			at := time.Now()
+			if strings.TrimSpace(note.Time) != "" {
+				var err error
+				at, err = time.ParseInLocation(noteTimeFormat, strings.TrimSpace(note.Time), time.Local)
+				if err != nil {
+					return nil, fmt.Errorf("Invalid note time %s, expected a time like 2026-10-01 15:04", note.Time)
+				}
+			}
+			notes = append(notes, Note{Time: at, Text: text})
+		}
+		todo.Notes = notes
+	}
+
+	if strings.Join(saved.Tags, ",") != strings.Join(opened.Tags, ",") {
+		todo.Tags = saved.Tags
+		if todo.Tags == nil {
+			todo.Tags = []string{}
+		}
+	}
+
+	if saved.Recur != opened.Recur {
+		err := setRecurrence(todo, saved.Recur)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Done goes last, completing may depend on the edited subtasks
+	if saved.Done != opened.Done {
+		return todo.markDone(saved.Done)
+	}
+
+	return nil, nil
+}
+
+func subtasksEqual(a []Subtask, b []Subtask) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func notesEqual(a []editableNote, b []editableNote) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func editTodo(opts Opts, repo Repository) error {
+	id, err := idFromOpts(opts, repo)
+	if err != nil {
+		return err
+	}
+	todo, err := repo.GetTodo(UserKey, id)
+	if err != nil {
+		return err
+	}
+
+	opened := toEditable(todo)
+	data, err := yaml.Marshal(opened)
+	if err != nil {
+		return err
+	}
+
+	edited, err := runEditor(data, ".yaml")
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(edited, data) {
+		fmt.Println("No changes")
+		return nil
+	}
+
+	var saved editableTodo
+	err = yaml.Unmarshal(edited, &saved)
+	if err != nil {
+		return fmt.Errorf("Unable to read the edited todo: %v", err)
+	}
+
+	if saved.Project != opened.Project && projectNameRe.MatchString(saved.Project) {
+		err = ensureProject(saved.Project, repo)
+		if err != nil {
+			return err
+		}
+	}
+
+	return repo.MutateAndCreateTodo(UserKey, id, func(todo *Todo) (*Todo, error) {
+		return applyEdit(todo, opened, saved)
+	})
+}
+
+// runEditor opens content in $EDITOR, vi if it is not set, and returns
+// the content as it was saved
+func runEditor(content []byte, suffix string) ([]byte, error) {
+	file, err := ioutil.TempFile("", "todo-*"+suffix)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(file.Name())
+
+	_, err = file.Write(content)
+	if err == nil {
+		err = file.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	editor := os.Getenv("EDITOR")
+	if editor == "" {
+		editor = "vi"
+	}
+
+	// EDITOR may carry arguments, like "code --wait"
+	parts := strings.Fields(editor)
+	cmd := exec.Command(parts[0], append(parts[1:], file.Name())...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	if err != nil {
+		return nil, fmt.Errorf("Editor %s failed: %v", editor, err)
+	}
+
+	return ioutil.ReadFile(file.Name())
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestApplyEdit(t *testing.T) {
+	stored := newTestTodo("Paint the fence", today(), "home")
+	stored.Estimate = 2
+	opened := toEditable(stored)
+
+	// Changed in the store while the editor was open
+	stored.Estimate = 3
+
+	saved := toEditable(stored)
+	saved.Estimate = opened.Estimate
+	saved.Title = "Paint the shed"
+	saved.Due = today().AddDate(0, 0, 1).Format("2006-01-02")
+	saved.Tags = nil
+	saved.Done = true
+
+	todo := stored
+	created, err := applyEdit(&todo, opened, saved)
+	if err != nil {
+		t.Fatalf("applyEdit: %v", err)
+	}
+	if created != nil {
+		t.Errorf("applyEdit created %+v for a todo that doesn't recur", created)
+	}
+
+	if todo.Title != "Paint the shed" || !todo.Due.Equal(today().AddDate(0, 0, 1)) || !todo.Done {
+		t.Errorf("applyEdit left %+v, want the edited title, due and done", todo)
+	}
+	if todo.Estimate != 3 {
+		t.Errorf("applyEdit set estimate %v, want the unedited 3 kept", todo.Estimate)
+	}
+	if !reflect.DeepEqual(todo.Tags, []string{}) {
+		t.Errorf("applyEdit set tags %#v, want none", todo.Tags)
+	}
+}
+
+func TestApplyEditInvalid(t *testing.T) {
+	stored := newTestTodo("Paint the fence", today())
+	opened := toEditable(stored)
+
+	tests := map[string]func(e *editableTodo){
+		"empty title": func(e *editableTodo) { e.Title = "  " },
+		"invalid due": func(e *editableTodo) { e.Due = "someday" },
+	}
+
+	for name, edit := range tests {
+		saved := toEditable(stored)
+		edit(&saved)
+
+		todo := stored
+		if _, err := applyEdit(&todo, opened, saved); err == nil {
+			t.Errorf("applyEdit with %s, want an error", name)
+		}
+	}
+}
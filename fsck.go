@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// IndexChecker is implemented by stores that maintain their own index
+// structures and can verify them and rebuild them from the todos
+type IndexChecker interface {
+	CheckIndexes(repair bool) ([]IndexProblem, error)
+}
+
+// IndexProblem struct describes one inconsistency found in a store
+type IndexProblem struct {
+	UserID string
+	Kind   string
+	Detail string
+}
+
+const (
+	// OrphanedEntry problem, an index entry with no matching todo
+	OrphanedEntry = "orphaned index entry"
+	// MissingEntry problem, a todo not present in an index it belongs to
+	MissingEntry = "missing index entry"
+	// InvalidEntry problem, an index entry holding the wrong value
+	InvalidEntry = "invalid index entry"
+	// UndecodableTodo problem, a todo record that is not valid JSON
+	UndecodableTodo = "undecodable todo"
+)
+
+var _ IndexChecker = &TodoRepo{}
+
+// CheckIndexes method scans every user bucket and compares the date,
+// pending, tag and search buckets with what the todos in it call for.
+// With repair set all index buckets are dropped and rebuilt from the
+// todos, in the same transaction. Undecodable todos are reported but
+// left in place
+func (r *TodoRepo) CheckIndexes(repair bool) ([]IndexProblem, error) {
+	problems := []IndexProblem{}
+
+	check := func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, userBucket *bolt.Bucket) error {
+			if !isUserBucket(name) {
+				return nil
+			}
+
+			userID := string(name)
+			todos, expected := []Todo{}, map[string]bool{}
+
+			c := userBucket.Cursor()
+			for k, v := c.First(); k != nil; k, v = c.Next() {
+				if v == nil {
+					continue
+				}
+
+				todo, err := makeTodo(v)
+				if err != nil {
+					problems = append(problems, IndexProblem{userID, UndecodableTodo, fmt.Sprintf("%s: %v", k, err)})
+					continue
+				}
+
+				todos = append(todos, todo)
+				for _, e := range indexEntries(todo) {
+					expected[e.String()] = true
+				}
+			}
+
+			found := map[string]bool{}
+			walkIndexEntries(userBucket, func(e indexEntry, value []byte) {
+				found[e.String()] = true
+				switch {
+				case !expected[e.String()]:
+					problems = append(problems, IndexProblem{userID, OrphanedEntry, e.String()})
+				case string(value) != string(e.key):
+					problems = append(problems, IndexProblem{userID, InvalidEntry, fmt.Sprintf("%s holds %q", e, value)})
+				}
+			})
+
+			for _, todo := range todos {
+				for _, e := range indexEntries(todo) {
+					if !found[e.String()] {
+						problems = append(problems, IndexProblem{userID, MissingEntry, e.String()})
+					}
+				}
+			}
+
+			if !repair {
+				return nil
+			}
+			return rebuildIndexes(userBucket, todos)
+		})
+	}
+
+	var err error
+	if repair {
+		err = r.db.Update(check)
+	} else {
+		err = r.db.View(check)
+	}
+
+	return problems, err
+}
+
+// rebuildIndexes drops every index bucket of the user bucket and writes
+// the index entries of the given todos again
+func rebuildIndexes(userBucket *bolt.Bucket, todos []Todo) error {
+	names := [][]byte{}
+	c := userBucket.Cursor()
+	for k, v := c.First(); k != nil; k, v = c.Next() {
+		if v == nil && isIndexBucket(k) {
+			names = append(names, append([]byte{}, k...))
+		}
+	}
+
+	for _, name := range names {
+		err := userBucket.DeleteBucket(name)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, todo := range todos {
+		for _, e := range indexEntries(todo) {
+			err := putIndexEntry(userBucket, e)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
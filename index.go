@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// indexEntry is a key in one of the index buckets nested in a user
+// bucket. path is the chain of bucket names below the user bucket and
+// the key and value are both the todo ID
+type indexEntry struct {
+	path [][]byte
+	key  []byte
+}
+
+// indexEntries returns the index entries the todo should have. It is
+// the single description of the bbolt index layout, used to write the
+// indexes and to check them
+func indexEntries(t Todo) []indexEntry {
+	entries := []indexEntry{
+		{path: [][]byte{t.due()}, key: t.id()},
+	}
+
+	if !t.Done {
+		entries = append(entries, indexEntry{path: [][]byte{PendingKey}, key: t.id()})
+	}
+
+	for _, tag := range t.Tags {
+		if tag != "" {
+			entries = append(entries, indexEntry{path: [][]byte{TagsKey, []byte(tag)}, key: t.id()})
+		}
+	}
+
+	for _, word := range t.searchWords() {
+		entries = append(entries, indexEntry{path: [][]byte{SearchKey, []byte(word)}, key: t.id()})
+	}
+
+	return entries
+}
+
+// isIndexBucket tells if the bucket nested in a user bucket under name
+// holds index entries
+func isIndexBucket(name []byte) bool {
+	return isDateKey(name) || bytes.Equal(name, PendingKey) || bytes.Equal(name, TagsKey) ||
+		bytes.Equal(name, SearchKey)
+}
+
+func (e indexEntry) String() string {
+	return string(bytes.Join(append(e.path, e.key), []byte("/")))
+}
+
+func putIndexEntry(userBucket *bolt.Bucket, e indexEntry) error {
+	bucket := userBucket
+	for _, name := range e.path {
+		var err error
+		bucket, err = bucket.CreateBucketIfNotExists(name)
+		if err != nil {
+			return err
+		}
+	}
+
+	return bucket.Put(e.key, e.key)
+}
+
+// deleteIndexEntry removes the entry, an entry that is already gone is
+// not an error. Buckets on its path left empty are removed as well, so
+// that dates, tags and words no todo has any more do not linger
+func deleteIndexEntry(userBucket *bolt.Bucket, e indexEntry) error {
+	buckets := []*bolt.Bucket{userBucket}
+	for _, name := range e.path {
+		bucket := buckets[len(buckets)-1].Bucket(name)
+		if bucket == nil {
+			return nil
+		}
+		buckets = append(buckets, bucket)
+	}
+
+	err := buckets[len(buckets)-1].Delete(e.key)
+	if err != nil {
+		return err
+	}
+
+	for i := len(e.path) - 1; i >= 0; i-- {
+		if k, _ := buckets[i+1].Cursor().First(); k != nil {
+			break
+		}
+
+		err = buckets[i].DeleteBucket(e.path[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// walkIndexEntries calls fn for every key found in the index buckets of
+// the user bucket along with the value stored for it
+func walkIndexEntries(userBucket *bolt.Bucket, fn func(e indexEntry, value []byte)) {
+	var walk func(bucket *bolt.Bucket, path [][]byte)
+	walk = func(bucket *bolt.Bucket, path [][]byte) {
+		c := bucket.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			if v == nil && bucket.Bucket(k) != nil {
+				walk(bucket.Bucket(k), append(append([][]byte{}, path...), k))
+				continue
+			}
+			fn(indexEntry{path: path, key: k}, v)
+		}
+	}
+
+	c := userBucket.Cursor()
+	for k, v := c.First(); k != nil; k, v = c.Next() {
+		if v == nil && isIndexBucket(k) {
+			walk(userBucket.Bucket(k), [][]byte{k})
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestIndexEntries(t *testing.T) {
+	pending := newTestTodo("Review the budget", today(), "work", "q4")
+	done := newTestTodo("File taxes", today())
+	done.Done = true
+	done.Notes = []Note{{Text: "Receipts in the blue folder"}}
+
+	due := string(pending.due())
+	tests := []struct {
+		todo Todo
+		want []string
+	}{
+		{pending, []string{
+			due + "/" + pending.ID,
+			"pending/" + pending.ID,
+			"search/budget/" + pending.ID,
+			"search/review/" + pending.ID,
+			"search/the/" + pending.ID,
+			"tags/q4/" + pending.ID,
+			"tags/work/" + pending.ID,
+		}},
+		{done, []string{
+			due + "/" + done.ID,
+			"search/blue/" + done.ID,
+			"search/file/" + done.ID,
+			"search/folder/" + done.ID,
+			"search/in/" + done.ID,
+			"search/receipts/" + done.ID,
+			"search/taxes/" + done.ID,
+			"search/the/" + done.ID,
+		}},
+	}
+
+	for _, test := range tests {
+		got := []string{}
+		for _, e := range indexEntries(test.todo) {
+			got = append(got, e.String())
+		}
+		sort.Strings(got)
+
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("indexEntries(%s) = %v, want %v", test.todo.Title, got, test.want)
+		}
+	}
+}
+
+func TestCheckIndexes(t *testing.T) {
+	first := newTestTodo("Book flights", today(), "travel")
+	second := newTestTodo("Renew passport", today(), "travel")
+
+	tests := []struct {
+		name   string
+		damage func(userBucket *bolt.Bucket) error
+		kind   string
+	}{
+		{"consistent", func(userBucket *bolt.Bucket) error {
+			return nil
+		}, ""},
+		{"missing pending entry", func(userBucket *bolt.Bucket) error {
+			return userBucket.Bucket(PendingKey).Delete(first.id())
+		}, MissingEntry},
+		{"missing tag entry", func(userBucket *bolt.Bucket) error {
+			return userBucket.Bucket(TagsKey).Bucket([]byte("travel")).Delete(second.id())
+		}, MissingEntry},
+		{"orphaned date entry", func(userBucket *bolt.Bucket) error {
+			return putIndexEntry(userBucket, indexEntry{path: [][]byte{[]byte("2020-01-01")}, key: []byte("gone")})
+		}, OrphanedEntry},
+		{"invalid entry value", func(userBucket *bolt.Bucket) error {
+			return userBucket.Bucket(PendingKey).Put(first.id(), []byte("other"))
+		}, InvalidEntry},
+		{"undecodable todo", func(userBucket *bolt.Bucket) error {
+			return userBucket.Put(second.id(), []byte("{not json"))
+		}, UndecodableTodo},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			boltRepo := openTestBolt(t, filepath.Join(t.TempDir(), "todo.db"))
+			createTodos(t, boltRepo, first, second)
+
+			err := boltRepo.db.Update(func(tx *bolt.Tx) error {
+				return test.damage(tx.Bucket([]byte(UserKey)))
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			problems, err := boltRepo.CheckIndexes(false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.kind == "" {
+				if len(problems) != 0 {
+					t.Fatalf("CheckIndexes found %v, want no problems", problems)
+				}
+				return
+			}
+			if len(problems) == 0 || problems[0].Kind != test.kind {
+				t.Fatalf("CheckIndexes found %v, want a %s first", problems, test.kind)
+			}
+
+			_, err = boltRepo.CheckIndexes(true)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			problems, err = boltRepo.CheckIndexes(false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, problem := range problems {
+				if problem.Kind != UndecodableTodo {
+					t.Errorf("after repair CheckIndexes found %v", problem)
+				}
+			}
+		})
+	}
+}
+
+func TestDeleteIndexEntryRemovesEmptyBuckets(t *testing.T) {
+	repo := openTestBolt(t, filepath.Join(t.TempDir(), "todo.db"))
+	todo := newTestTodo("Call the plumber", today(), "home")
+	createTodos(t, repo, todo)
+
+	err := repo.MutateTodo(UserKey, todo.ID, func(moved *Todo) error {
+		moved.Tags = []string{}
+		moved.Due = moved.Due.AddDate(0, 0, 1)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = repo.db.View(func(tx *bolt.Tx) error {
+		userBucket := tx.Bucket([]byte(UserKey))
+		if userBucket.Bucket(TagsKey) != nil {
+			t.Errorf("tags bucket left after the last tag was removed")
+		}
+		if userBucket.Bucket(todo.due()) != nil {
+			t.Errorf("date bucket %s left after the todo moved", todo.due())
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
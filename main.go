@@ -3,24 +3,49 @@ package main
 import (
 	"log"
 	"os"
-
-	bolt "go.etcd.io/bbolt"
 )
 
 func main() {
-	db, err := bolt.Open(getDbPath(), 0600, nil)
+	cfg, err := loadConfig(getConfigPath())
+	if err != nil {
+		log.Fatal(err)
+	}
+	config = cfg
+
+	store, args, err := getStoreSpec(os.Args, config.Store)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	opts, err := getOpts(args)
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer db.Close()
 
-	repo := &TodoRepo{}
-	repo.Init(db)
+	// Migration opens the stores it is given, the default store may be
+	// one of them and bbolt allows a single open handle per file
+	if opts.option == MigrateStore {
+		err = migrateStore(opts)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 
-	opts, err := getOpts(os.Args)
+	repo, err := openRepository(store)
 	if err != nil {
 		log.Fatal(err)
 	}
+	defer repo.Close()
+
+	// Overdue todos are moved before anything is listed, fsck has to see
+	// the store as it is
+	if config.Rollover == RolloverAuto && opts.option != RolloverTodos && opts.option != CheckStore {
+		_, err = rollover(repo)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
 
 	operation := OperationMap[opts.option]
 	if operation == nil {
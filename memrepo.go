@@ -0,0 +1,523 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryRepo struct is a Repository that keeps the todos in memory.
+// Nothing is persisted, it is meant for tests and for trying out
+// alternative stores against the operations
+type MemoryRepo struct {
+	mu          sync.Mutex
+	todos       map[string]map[string]Todo
+	sessions    map[string][]Session
+	projects    map[string]map[string]Project
+	listMapping map[string]string
+}
+
+var _ Repository = &MemoryRepo{}
+
+// Init method
+func (r *MemoryRepo) Init() {
+	r.todos = map[string]map[string]Todo{}
+	r.sessions = map[string][]Session{}
+	r.projects = map[string]map[string]Project{}
+	r.listMapping = map[string]string{}
+}
+
+// Close method
+func (r *MemoryRepo) Close() error {
+	return nil
+}
+
+// CreateTodo method
+func (r *MemoryRepo) CreateTodo(userID string, t Todo) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.todos[userID] == nil {
+		r.todos[userID] = map[string]Todo{}
+	}
+	prepareWrite(nil, &t, time.Now())
+	r.todos[userID][t.ID] = t
+
+	return nil
+}
+
+// GetPendingTodos method
+func (r *MemoryRepo) GetPendingTodos(userID string) ([]Todo, error) {
+	return r.filter(userID, func(t Todo) bool {
+		return !t.Done
+	}), nil
+}
+
+// GetTodosByDate method
+func (r *MemoryRepo) GetTodosByDate(userID string, date time.Time) ([]Todo, error) {
+	key := date.Format("2006-01-02")
+	return r.filter(userID, func(t Todo) bool {
+		return string(t.due()) == key
+	}), nil
+}
+
+// GetTodosInRange method
+func (r *MemoryRepo) GetTodosInRange(userID string, from time.Time, to time.Time) ([]Todo, error) {
+	fromKey := from.Format("2006-01-02")
+	toKey := to.Format("2006-01-02")
+	todos := r.filter(userID, func(t Todo) bool {
+		return string(t.due()) >= fromKey && string(t.due()) <= toKey
+	})
+
+	sort.SliceStable(todos, func(i, j int) bool {
+		return string(todos[i].due()) < string(todos[j].due())
+	})
+
+	return todos, nil
+}
+
+// GetAllTodos method
+func (r *MemoryRepo) GetAllTodos(userID string) ([]Todo, error) {
+	return r.filter(userID, func(t Todo) bool {
+		return true
+	}), nil
+}
+
+// ForEachTodo method
+func (r *MemoryRepo) ForEachTodo(userID string, fn func(Todo) error) error {
+	todos, _ := r.GetAllTodos(userID)
+	for _, todo := range todos {
+		err := fn(todo)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetTodosCompletedInRange method
+func (r *MemoryRepo) GetTodosCompletedInRange(userID string, from time.Time, to time.Time) ([]Todo, error) {
+	todos, _ := r.GetAllTodos(userID)
+	return completedInRange(todos, from, to), nil
+}
+
+// GetTodosByTag method
+func (r *MemoryRepo) GetTodosByTag(userID string, tag string) ([]Todo, error) {
+	return r.filter(userID, func(t Todo) bool {
+		for _, tg := range t.Tags {
+			if tg == tag {
+				return true
+			}
+		}
+		return false
+	}), nil
+}
+
+// GetTags method
+func (r *MemoryRepo) GetTags(userID string) ([]string, error) {
+	todos, _ := r.GetAllTodos(userID)
+
+	seen := map[string]bool{}
+	tags := []string{}
+	for _, todo := range todos {
+		for _, tag := range todo.Tags {
+			if tag != "" && !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+	sort.Strings(tags)
+
+	return tags, nil
+}
+
+// GetTodosByWord method
+func (r *MemoryRepo) GetTodosByWord(userID string, word string, prefix bool) ([]Todo, error) {
+	return r.filter(userID, func(t Todo) bool {
+		for _, w := range t.searchWords() {
+			if w == word || prefix && strings.HasPrefix(w, word) {
+				return true
+			}
+		}
+		return false
+	}), nil
+}
+
+// GetUsers method
+func (r *MemoryRepo) GetUsers() ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	users := []string{}
+	for userID := range r.todos {
+		users = append(users, userID)
+	}
+	sort.Strings(users)
+
+	return users, nil
+}
+
+// GetTodo method
+func (r *MemoryRepo) GetTodo(userID string, id string) (Todo, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	todo, ok := r.todos[userID][id]
+	if !ok {
+		return Todo{}, fmt.Errorf("No todo found for ID: %s", id)
+	}
+
+	return todo, nil
+}
+
+// SetTodoDone method, completing a recurring todo also creates its next
+// occurrence
+func (r *MemoryRepo) SetTodoDone(userID string, todoID string, status bool) error {
+	return r.MutateAndCreateTodo(userID, todoID, func(todo *Todo) (*Todo, error) {
+		return todo.markDone(status)
+	})
+}
+
+// SetTodoEstimate method
+func (r *MemoryRepo) SetTodoEstimate(userID string, todoID string, estimate float32) error {
+	return r.MutateTodo(userID, todoID, func(todo *Todo) error {
+		todo.Estimate = estimate
+		return nil
+	})
+}
+
+// SetTodoDue method
+func (r *MemoryRepo) SetTodoDue(userID string, todoID string, due time.Time) error {
+	return r.MutateTodo(userID, todoID, func(todo *Todo) error {
+		todo.Due = due
+		return nil
+	})
+}
+
+// SetTodoTags method
+func (r *MemoryRepo) SetTodoTags(userID string, todoID string, tags []string) error {
+	return r.MutateTodo(userID, todoID, func(todo *Todo) error {
+		todo.Tags = tags
+		return nil
+	})
+}
+
+// DeleteTodo method
+func (r *MemoryRepo) DeleteTodo(userID string, todoID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.todos[userID][todoID]; !ok {
+		return fmt.Errorf("No todo found for ID: %s", todoID)
+	}
+	delete(r.todos[userID], todoID)
+
+	sessions := []Session{}
+	for _, s := range r.sessions[userID] {
+		if s.TodoID != todoID {
+			sessions = append(sessions, s)
+		}
+	}
+	r.sessions[userID] = sessions
+
+	return nil
+}
+
+// UpdateTodo method
+func (r *MemoryRepo) UpdateTodo(userID string, todoID string, todo Todo) error {
+	return r.MutateTodo(userID, todoID, func(t *Todo) error {
+		*t = todo
+		return nil
+	})
+}
+
+// SetListMapping method replaces the mapping of the previous listing
+func (r *MemoryRepo) SetListMapping(mapping map[string]string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.listMapping = map[string]string{}
+	for k, v := range mapping {
+		r.listMapping[k] = v
+	}
+}
+
+// GetListMapping method
+func (r *MemoryRepo) GetListMapping() map[string]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	listMapping := map[string]string{}
+	for k, v := range r.listMapping {
+		listMapping[k] = v
+	}
+
+	return listMapping
+}
+
+// filter returns the todos of the user matching keep, ordered by ID
+// like the bbolt buckets are
+func (r *MemoryRepo) filter(userID string, keep func(Todo) bool) []Todo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	todos := []Todo{}
+	for _, todo := range r.todos[userID] {
+		if keep(todo) {
+			todos = append(todos, todo)
+		}
+	}
+
+	sort.Slice(todos, func(i, j int) bool {
+		return todos[i].ID < todos[j].ID
+	})
+
+	return todos
+}
+
+// MutateTodo method
+func (r *MemoryRepo) MutateTodo(userID string, todoID string, change func(*Todo) error) error {
+	return r.MutateAndCreateTodo(userID, todoID, func(todo *Todo) (*Todo, error) {
+		return nil, change(todo)
+	})
+}
+
+// MutateAndCreateTodo method is MutateTodo where change can also
+// return a new todo to be created along with the update
+func (r *MemoryRepo) MutateAndCreateTodo(userID string, todoID string, change func(*Todo) (*Todo, error)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	old, ok := r.todos[userID][todoID]
+	if !ok {
+		return fmt.Errorf("No todo found for ID: %s", todoID)
+	}
+
+	todo := copyTodo(old)
+	created, err := change(&todo)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	prepareWrite(&old, &todo, now)
+	delete(r.todos[userID], todoID)
+	r.todos[userID][todo.ID] = todo
+	if created != nil {
+		prepareWrite(nil, created, now)
+		r.todos[userID][created.ID] = *created
+	}
+
+	return nil
+}
+
+// copyTodo returns a copy of t that shares none of its slices, so a
+// change failing halfway leaves the stored todo as it was
+func copyTodo(t Todo) Todo {
+	t.Tags = append(t.Tags[:0:0], t.Tags...)
+	t.Contexts = append(t.Contexts[:0:0], t.Contexts...)
+	t.Notes = append(t.Notes[:0:0], t.Notes...)
+	t.Subtasks = append(t.Subtasks[:0:0], t.Subtasks...)
+	t.DependsOn = append(t.DependsOn[:0:0], t.DependsOn...)
+	return t
+}
+
+// MutateTodos method applies change to each of the todos, none of them
+// is written if change fails for any. A todo listed more than once is
+// changed once
+func (r *MemoryRepo) MutateTodos(userID string, todoIDs []string, change func(*Todo) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.mutateTodos(userID, uniqueIDs(todoIDs), change)
+}
+
+// ReplaceTag method
+func (r *MemoryRepo) ReplaceTag(userID string, from string, to string, merge bool) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tagged, existing := []string{}, 0
+	for id, todo := range r.todos[userID] {
+		if hasTag(todo.Tags, from) {
+			tagged = append(tagged, id)
+		}
+		if hasTag(todo.Tags, to) {
+			existing++
+		}
+	}
+
+	err := checkReplaceTag(from, to, merge, len(tagged), existing)
+	if err != nil {
+		return 0, err
+	}
+
+	err = r.mutateTodos(userID, tagged, func(todo *Todo) error {
+		todo.replaceTag(from, to)
+		return nil
+	})
+	return len(tagged), err
+}
+
+// mutateTodos is MutateTodos for callers holding the lock
+func (r *MemoryRepo) mutateTodos(userID string, todoIDs []string, change func(*Todo) error) error {
+	changed := []Todo{}
+	for _, todoID := range todoIDs {
+		old, ok := r.todos[userID][todoID]
+		if !ok {
+			return fmt.Errorf("No todo found for ID: %s", todoID)
+		}
+
+		todo := copyTodo(old)
+		err := change(&todo)
+		if err != nil {
+			return err
+		}
+
+		prepareWrite(&old, &todo, time.Now())
+		changed = append(changed, todo)
+	}
+
+	for i, todo := range changed {
+		delete(r.todos[userID], todoIDs[i])
+		r.todos[userID][todo.ID] = todo
+	}
+
+	return nil
+}
+
+// AddSession method
+func (r *MemoryRepo) AddSession(userID string, session Session) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.todos[userID][session.TodoID]; !ok {
+		return fmt.Errorf("No todo found for ID: %s", session.TodoID)
+	}
+
+	if session.running() {
+		for _, s := range r.sessions[userID] {
+			if s.running() {
+				return fmt.Errorf("A timer is already running for todo %s", s.TodoID)
+			}
+		}
+	}
+
+	r.sessions[userID] = append(r.sessions[userID], session)
+	return nil
+}
+
+// StopSession method
+func (r *MemoryRepo) StopSession(userID string, end time.Time) (Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, s := range r.sessions[userID] {
+		if s.running() {
+			r.sessions[userID][i].End = end
+			return r.sessions[userID][i], nil
+		}
+	}
+
+	return Session{}, fmt.Errorf("No timer is running")
+}
+
+// GetRunningSession method
+func (r *MemoryRepo) GetRunningSession(userID string) (*Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, s := range r.sessions[userID] {
+		if s.running() {
+			return &s, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// GetSessions method
+func (r *MemoryRepo) GetSessions(userID string, todoID string) ([]Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sessions := []Session{}
+	for _, s := range r.sessions[userID] {
+		if s.TodoID == todoID {
+			sessions = append(sessions, s)
+		}
+	}
+
+	sort.SliceStable(sessions, func(i, j int) bool {
+		return sessions[i].Start.Before(sessions[j].Start)
+	})
+
+	return sessions, nil
+}
+
+// SaveProject method
+func (r *MemoryRepo) SaveProject(userID string, project Project) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.projects[userID] == nil {
+		r.projects[userID] = map[string]Project{}
+	}
+	r.projects[userID][project.Name] = project
+
+	return nil
+}
+
+// GetProject method
+func (r *MemoryRepo) GetProject(userID string, name string) (Project, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	project, ok := r.projects[userID][name]
+	if !ok {
+		return Project{}, fmt.Errorf("No project found named %s", name)
+	}
+
+	return project, nil
+}
+
+// GetProjects method
+func (r *MemoryRepo) GetProjects(userID string) ([]Project, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	projects := []Project{}
+	for _, project := range r.projects[userID] {
+		projects = append(projects, project)
+	}
+
+	sort.Slice(projects, func(i, j int) bool {
+		return projects[i].Name < projects[j].Name
+	})
+
+	return projects, nil
+}
+
+// DeleteProject method
+func (r *MemoryRepo) DeleteProject(userID string, name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.projects[userID][name]; !ok {
+		return fmt.Errorf("No project found named %s", name)
+	}
+	delete(r.projects[userID], name)
+
+	return nil
+}
+
+// GetTodosByProject method
+func (r *MemoryRepo) GetTodosByProject(userID string, name string) ([]Todo, error) {
+	return r.filter(userID, func(t Todo) bool {
+		return t.Project == name
+	}), nil
+}
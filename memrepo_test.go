@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMutateTodosKeepsStoredTodosOnFailure(t *testing.T) {
+	for name, repo := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			first := newTestTodo("first", today(), "home", "errands")
+			first.Notes = []Note{{Time: time.Now().UTC().Truncate(time.Second), Text: "call first"}}
+			first.Subtasks = []Subtask{{Title: "list"}}
+			second := newTestTodo("second", today())
+			createTodos(t, repo, first, second)
+
+			err := repo.MutateTodos(UserKey, []string{first.ID, second.ID}, func(todo *Todo) error {
+				if todo.ID == second.ID {
+					return fmt.Errorf("refused")
+				}
+				todo.Title = "changed"
+				todo.Tags[0] = "work"
+				todo.Notes[0].Text = "changed"
+				todo.Subtasks[0].Done = true
+				return nil
+			})
+			if err == nil {
+				t.Fatalf("MutateTodos: expected the error of the change")
+			}
+
+			got, err := repo.GetTodo(UserKey, first.ID)
+			if err != nil {
+				t.Fatalf("GetTodo: %v", err)
+			}
+			if got.Title != "first" || !reflect.DeepEqual(got.Tags, first.Tags) ||
+				got.Notes[0].Text != "call first" || got.Subtasks[0].Done {
+				t.Errorf("todo changed by a failed MutateTodos: %+v", got)
+			}
+		})
+	}
+}
+
+func TestMutateAndCreateTodoKeepsStoredTodoOnFailure(t *testing.T) {
+	for name, repo := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			todo := newTestTodo("first", today(), "home")
+			todo.Contexts = []string{"phone"}
+			todo.DependsOn = []string{"other"}
+			createTodos(t, repo, todo)
+
+			err := repo.MutateAndCreateTodo(UserKey, todo.ID, func(changed *Todo) (*Todo, error) {
+				changed.Tags[0] = "work"
+				changed.Contexts[0] = "office"
+				changed.DependsOn[0] = "another"
+				return nil, fmt.Errorf("refused")
+			})
+			if err == nil {
+				t.Fatalf("MutateAndCreateTodo: expected the error of the change")
+			}
+
+			got, err := repo.GetTodo(UserKey, todo.ID)
+			if err != nil {
+				t.Fatalf("GetTodo: %v", err)
+			}
+			if got.Tags[0] != "home" || got.Contexts[0] != "phone" || got.DependsOn[0] != "other" {
+				t.Errorf("todo changed by a failed MutateAndCreateTodo: %+v", got)
+			}
+		})
+	}
+}
+
+func TestMutateAndCreateTodo(t *testing.T) {
+	for name, repo := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			todo := newTestTodo("first", today())
+			createTodos(t, repo, todo)
+
+			next := newTestTodo("next", today().AddDate(0, 0, 1))
+			err := repo.MutateAndCreateTodo(UserKey, todo.ID, func(changed *Todo) (*Todo, error) {
+				changed.Done = true
+				return &next, nil
+			})
+			if err != nil {
+				t.Fatalf("MutateAndCreateTodo: %v", err)
+			}
+
+			pending, err := repo.GetPendingTodos(UserKey)
+			if err != nil {
+				t.Fatalf("GetPendingTodos: %v", err)
+			}
+			if got := titlesOf(pending); !reflect.DeepEqual(got, []string{"next"}) {
+				t.Errorf("pending todos = %v, want [next]", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+)
+
+// migrateStore copies every todo of every user from one store to
+// another along with its work sessions, keeping IDs and all fields as
+// they are. Both stores are given as specs for openRepository
+func migrateStore(opts Opts) error {
+	fromSpec := opts.params["from"].(string)
+	toSpec := opts.params["to"].(string)
+	if sameStore(fromSpec, toSpec) {
+		return fmt.Errorf("Source and destination are the same store: %s", fromSpec)
+	}
+
+	from, err := openRepository(fromSpec)
+	if err != nil {
+		return err
+	}
+	defer from.Close()
+
+	to, err := openRepository(toSpec)
+	if err != nil {
+		return err
+	}
+	defer to.Close()
+
+	users, total, err := copyStore(from, to)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%d todos of %d users migrated from %s to %s\n", total, users, fromSpec, toSpec)
+	return nil
+}
+
+// copyStore streams the todos of every user from one store into the
+// other and returns how many users and todos were copied. The
+// destination must not hold todos for the migrated users yet, so that
+// the counts can be verified once the copy is done
+func copyStore(from Repository, to Repository) (int, int, error) {
+	users, err := from.GetUsers()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for _, userID := range users {
+		existing, err := countTodos(to, userID)
+		if err != nil {
+			return 0, 0, err
+		}
+		if existing > 0 {
+			return 0, 0, fmt.Errorf("Destination already has %d todos for user %s", existing, userID)
+		}
+	}
+
+	total, err := copyUsers(from, to, users)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%v. The destination holds a partial copy now, remove it before migrating again", err)
+	}
+
+	return len(users), total, nil
+}
+
+// copyUsers copies the projects, todos and sessions of the users and
+// returns how many todos were copied
+func copyUsers(from Repository, to Repository, users []string) (int, error) {
+	total := 0
+	for _, userID := range users {
+		projects, err := from.GetProjects(userID)
+		if err != nil {
+			return 0, err
+		}
+
+		for _, project := range projects {
+			err = to.SaveProject(userID, project)
+			if err != nil {
+				return 0, fmt.Errorf("Unable to migrate project %s of user %s: %v", project.Name, userID, err)
+			}
+		}
+
+		copied := 0
+		err = from.ForEachTodo(userID, func(todo Todo) error {
+			err := to.CreateTodo(userID, todo)
+			if err != nil {
+				return fmt.Errorf("Unable to migrate todo %s of user %s: %v", todo.ID, userID, err)
+			}
+
+			err = migrateSessions(from, to, userID, todo.ID)
+			if err != nil {
+				return fmt.Errorf("Unable to migrate sessions of todo %s of user %s: %v", todo.ID, userID, err)
+			}
+
+			copied++
+			return nil
+		})
+		if err != nil {
+			return 0, err
+		}
+
+		migrated, err := countTodos(to, userID)
+		if err != nil {
+			return 0, err
+		}
+		if migrated != copied {
+			return 0, fmt.Errorf("Count mismatch for user %s: %d todos in source, %d in destination",
+				userID, copied, migrated)
+		}
+
+		fmt.Printf("%s: %d todos migrated\n", userID, copied)
+		total += copied
+	}
+
+	return total, nil
+}
+
+// countTodos counts the todos of a user without loading them all
+func countTodos(repo Repository, userID string) (int, error) {
+	count := 0
+	err := repo.ForEachTodo(userID, func(Todo) error {
+		count++
+		return nil
+	})
+	return count, err
+}
+
+// migrateSessions copies the work sessions of a todo, a running session
+// keeps running in the destination
+func migrateSessions(from Repository, to Repository, userID string, todoID string) error {
+	sessions, err := from.GetSessions(userID, todoID)
+	if err != nil {
+		return err
+	}
+
+	for _, session := range sessions {
+		err = to.AddSession(userID, session)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newMemoryRepo() *MemoryRepo {
+	repo := &MemoryRepo{}
+	repo.Init()
+	return repo
+}
+
+func TestCopyStore(t *testing.T) {
+	from := newMemoryRepo()
+
+	pending := newTestTodo("Draft the proposal", today().AddDate(0, 0, 2), "work")
+	pending.Estimate = 3
+	pending.Project = "bid"
+	done := newTestTodo("Send the invoice", today().AddDate(0, 0, -1), "work", "money")
+	done.Done = true
+	other := newTestTodo("Plan the trip", today())
+
+	createTodos(t, from, pending, done)
+	err := from.CreateTodo("OTHER", other)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = from.SaveProject(UserKey, Project{Name: "bid", Status: ProjectActive})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now().Add(-2 * time.Hour).Truncate(time.Second)
+	sessions := []Session{
+		{TodoID: pending.ID, Start: start, End: start.Add(time.Hour)},
+		{TodoID: pending.ID, Start: start.Add(90 * time.Minute)},
+	}
+	for _, session := range sessions {
+		err = from.AddSession(UserKey, session)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	to := newMemoryRepo()
+	users, total, err := copyStore(from, to)
+	if err != nil {
+		t.Fatalf("copyStore: %v", err)
+	}
+	if users != 2 || total != 3 {
+		t.Errorf("copyStore copied %d todos of %d users, want 3 of 2", total, users)
+	}
+
+	for _, userID := range []string{UserKey, "OTHER"} {
+		want, _ := from.GetAllTodos(userID)
+		got, _ := to.GetAllTodos(userID)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("todos of %s = %+v, want %+v", userID, got, want)
+		}
+	}
+
+	gotSessions, _ := to.GetSessions(UserKey, pending.ID)
+	if !reflect.DeepEqual(gotSessions, sessions) {
+		t.Errorf("sessions = %+v, want %+v", gotSessions, sessions)
+	}
+
+	projects, _ := to.GetProjects(UserKey)
+	if len(projects) != 1 || projects[0].Name != "bid" {
+		t.Errorf("projects = %+v, want bid", projects)
+	}
+
+	_, _, err = copyStore(from, to)
+	if err == nil {
+		t.Errorf("copyStore into a store holding the todos already, want an error")
+	}
+}
+
+// failingRepo is a MemoryRepo refusing to create todos once it holds
+// limit of them
+type failingRepo struct {
+	*MemoryRepo
+	limit int
+}
+
+func (r failingRepo) CreateTodo(userID string, t Todo) error {
+	count, err := countTodos(r.MemoryRepo, userID)
+	if err != nil {
+		return err
+	}
+	if count >= r.limit {
+		return fmt.Errorf("disk full")
+	}
+	return r.MemoryRepo.CreateTodo(userID, t)
+}
+
+func TestCopyStorePartialFailure(t *testing.T) {
+	from := newMemoryRepo()
+	createTodos(t, from, newTestTodo("first", today()), newTestTodo("second", today()))
+
+	_, _, err := copyStore(from, failingRepo{newMemoryRepo(), 1})
+	if err == nil {
+		t.Fatalf("copyStore into a failing store, want an error")
+	}
+	if !strings.Contains(err.Error(), "disk full") || !strings.Contains(err.Error(), "remove it before migrating again") {
+		t.Errorf("copyStore error = %q, want the cause and how to recover", err)
+	}
+}
@@ -2,18 +2,105 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
+	"sort"
 	"strings"
 	"time"
 )
 
-// Todo struct
+// Todo struct. The JSON form is what the stores persist, renaming or
+// retyping a field needs a schema migration
 type Todo struct {
-	ID     string    `json:"id"`
-	Title  string    `json:"title"`
-	Done   bool      `json:"done"`
-	Due    time.Time `json:"due"`
-	Tags   []string  `json:"tags"`
-	Effort float32   `json:"duration"`
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	Done      bool      `json:"done"`
+	Due       time.Time `json:"due"`
+	DueZone   string    `json:"zone,omitempty"`
+	Postponed int       `json:"postponed,omitempty"`
+	Tags      []string  `json:"tags"`
+	Project   string    `json:"project,omitempty"`
+	Contexts  []string  `json:"contexts,omitempty"`
+	Estimate  float32   `json:"estimate"`
+	Priority  Priority  `json:"priority,omitempty"`
+	Notes     []Note    `json:"notes,omitempty"`
+	Subtasks  []Subtask `json:"subtasks,omitempty"`
+	DependsOn []string  `json:"depends,omitempty"`
+	Recur     string    `json:"recur,omitempty"`
+	SeriesID  string    `json:"series,omitempty"`
+	NextID    string    `json:"next,omitempty"`
+	Created   time.Time `json:"created,omitempty"`
+	Modified  time.Time `json:"modified,omitempty"`
+	Completed time.Time `json:"completed,omitempty"`
+}
+
+// Note struct is a timestamped free text note attached to a todo
+type Note struct {
+	Time time.Time `json:"time"`
+	Text string    `json:"text"`
+}
+
+// Subtask struct is a checklist item inside a todo
+type Subtask struct {
+	Title string `json:"title"`
+	Done  bool   `json:"done"`
+}
+
+// Priority type, a higher value is more urgent
+type Priority int
+
+const (
+	// PriorityNone is the priority of todos that were not given one
+	PriorityNone Priority = iota
+	// PriorityLow priority
+	PriorityLow
+	// PriorityMedium priority
+	PriorityMedium
+	// PriorityHigh priority
+	PriorityHigh
+)
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityHigh:
+		return "High"
+	case PriorityMedium:
+		return "Medium"
+	case PriorityLow:
+		return "Low"
+	default:
+		return "None"
+	}
+}
+
+// mark is the marker shown before the title in listings
+func (p Priority) mark() string {
+	switch p {
+	case PriorityHigh:
+		return "!!! "
+	case PriorityMedium:
+		return "!! "
+	case PriorityLow:
+		return "! "
+	default:
+		return ""
+	}
+}
+
+// toPriority reads a priority given as high, medium, low or none, or
+// their first letter, in any case
+func toPriority(str string) (Priority, error) {
+	switch strings.ToLower(str) {
+	case "high", "h":
+		return PriorityHigh, nil
+	case "medium", "med", "m":
+		return PriorityMedium, nil
+	case "low", "l":
+		return PriorityLow, nil
+	case "none", "n", "":
+		return PriorityNone, nil
+	default:
+		return PriorityNone, fmt.Errorf("Unknown priority %s, expected high, medium, low or none", str)
+	}
 }
 
 func (t Todo) id() []byte {
@@ -28,20 +115,174 @@ func (t Todo) data() []byte {
 	return d
 }
 
+// dueLocal returns the due time in the zone it was given in. Due is
+// stored in UTC with the zone name alongside; todos written before zones
+// were kept have no zone name and keep the offset they were stored with
+func (t Todo) dueLocal() time.Time {
+	if t.DueZone != "" {
+		if loc, err := zoneLocation(t.DueZone); err == nil {
+			return t.Due.In(loc)
+		}
+	}
+	return t.Due
+}
+
+// hasTime tells if the todo is due at a time of day rather than on a day
+func (t Todo) hasTime() bool {
+	due := t.dueLocal()
+	return due.Hour() != 0 || due.Minute() != 0
+}
+
+// due is the key of the date bucket, the due day in the zone the due
+// date was given in, so that it does not depend on the machine's zone
 func (t Todo) due() []byte {
-	return []byte(t.Due.Format("2006-01-02"))
+	return []byte(t.dueLocal().Format("2006-01-02"))
 }
 
 func (t Todo) datestr() string {
-	return t.Due.Format("2 Jan 2006")
+	if t.hasTime() {
+		return t.dueLocal().Format("2 Jan 2006 15:04 MST")
+	}
+	return t.dueLocal().Format("2 Jan 2006")
+}
+
+// overdue tells if the todo is pending and was due on a day before
+// today. Both days are taken in the zone of the due date, so a todo
+// keeps its meaning when the machine is in another zone
+func (t Todo) overdue() bool {
+	due := t.dueLocal()
+	return !t.Done && string(t.due()) < time.Now().In(due.Location()).Format("2006-01-02")
+}
+
+// duestr is the short due date shown in listings
+func (t Todo) duestr() string {
+	if t.hasTime() {
+		return t.dueLocal().Format("02 Jan 15:04")
+	}
+	return t.dueLocal().Format("02 Jan")
 }
 
 func (t Todo) tagsstr() string {
 	return strings.Join(t.Tags, ",")
 }
 
+func isDateKey(key []byte) bool {
+	_, err := time.Parse("2006-01-02", string(key))
+	return err == nil
+}
+
+// setDone marks the todo done or pending. Marking it done while it has
+// open subtasks is refused or completes the subtasks too, as set by the
+// subtasks config
+func (t *Todo) setDone(done bool) error {
+	open := t.openSubtasks()
+	if done && open > 0 {
+		switch config.Subtasks {
+		case SubtasksRequire:
+			return fmt.Errorf("%d of %d subtasks are still open", open, len(t.Subtasks))
+		case SubtasksComplete:
+			for i := range t.Subtasks {
+				t.Subtasks[i].Done = true
+			}
+		}
+	}
+
+	t.Done = done
+	return nil
+}
+
+func (t Todo) openSubtasks() int {
+	open := 0
+	for _, subtask := range t.Subtasks {
+		if !subtask.Done {
+			open++
+		}
+	}
+	return open
+}
+
+// progress is the subtask progress shown in listings, like [2/5]
+func (t Todo) progress() string {
+	if len(t.Subtasks) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("[%d/%d] ", len(t.Subtasks)-t.openSubtasks(), len(t.Subtasks))
+}
+
+// prepareWrite is called by the stores on a todo about to be written
+// over old, nil for a new todo. It stores a due time that was just set
+// in UTC along with its zone, and sets the created, modified and
+// completed times. Times already set on a new todo are kept so that
+// migrated todos keep their history
+func prepareWrite(old *Todo, todo *Todo, now time.Time) {
+	// Due times decoded from the store are in UTC, any other zone means
+	// the due time was set by the user
+	if todo.Due.Location() != time.UTC {
+		todo.DueZone = zoneName(todo.Due)
+		todo.Due = todo.Due.UTC()
+	}
+
+	if old == nil {
+		if todo.Created.IsZero() {
+			todo.Created = now
+		}
+		if todo.Modified.IsZero() {
+			todo.Modified = now
+		}
+		if todo.Done && todo.Completed.IsZero() {
+			todo.Completed = now
+		}
+		return
+	}
+
+	todo.Modified = now
+	if !todo.Done {
+		todo.Completed = time.Time{}
+	} else if !old.Done {
+		todo.Completed = now
+	}
+}
+
+// completedInRange returns the todos completed between from and to,
+// both days included, ordered by completion time
+func completedInRange(todos []Todo, from time.Time, to time.Time) []Todo {
+	completed := []Todo{}
+	for _, todo := range todos {
+		if !todo.Done || todo.Completed.IsZero() {
+			continue
+		}
+
+		day := startOfDay(todo.Completed.Local())
+		if !day.Before(from) && !day.After(to) {
+			completed = append(completed, todo)
+		}
+	}
+
+	sort.SliceStable(completed, func(i, j int) bool {
+		return completed[i].Completed.Before(completed[j].Completed)
+	})
+
+	return completed
+}
+
+// makeTodo decodes a stored todo. Todos written before the estimate and
+// the actual effort were kept apart have a single duration instead, it
+// is read as the estimate
 func makeTodo(data []byte) (Todo, error) {
-	var todo Todo
-	err := json.Unmarshal(data, &todo)
-	return todo, err
+	var stored struct {
+		Todo
+		Duration *float32 `json:"duration"`
+	}
+
+	err := json.Unmarshal(data, &stored)
+	if err != nil {
+		return Todo{}, err
+	}
+
+	todo := stored.Todo
+	if stored.Duration != nil {
+		todo.Estimate = *stored.Duration
+	}
+
+	return todo, nil
 }
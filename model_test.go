@@ -0,0 +1,65 @@
+package main
+
+import "testing"
+
+func TestToPriority(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Priority
+	}{
+		{"high", PriorityHigh},
+		{"H", PriorityHigh},
+		{"med", PriorityMedium},
+		{"Medium", PriorityMedium},
+		{"l", PriorityLow},
+		{"none", PriorityNone},
+		{"", PriorityNone},
+	}
+
+	for _, test := range tests {
+		got, err := toPriority(test.input)
+		if err != nil {
+			t.Errorf("toPriority(%q) returned error: %v", test.input, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("toPriority(%q) = %v, want %v", test.input, got, test.want)
+		}
+	}
+
+	if _, err := toPriority("urgent"); err == nil {
+		t.Errorf("toPriority(%q), want an error", "urgent")
+	}
+}
+
+func TestSetDoneWithOpenSubtasks(t *testing.T) {
+	saved := config
+	defer func() { config = saved }()
+
+	tests := []struct {
+		mode     string
+		err      bool
+		progress string
+	}{
+		{SubtasksIgnore, false, "[1/2] "},
+		{SubtasksRequire, true, "[1/2] "},
+		{SubtasksComplete, false, "[2/2] "},
+	}
+
+	for _, test := range tests {
+		config.Subtasks = test.mode
+
+		todo := newTestTodo("Move house", today())
+		todo.Subtasks = []Subtask{{Title: "pack", Done: true}, {Title: "clean"}}
+		err := todo.setDone(true)
+		if (err != nil) != test.err {
+			t.Errorf("setDone with subtasks %s returned error %v, want error %v", test.mode, err, test.err)
+		}
+		if todo.Done == test.err {
+			t.Errorf("setDone with subtasks %s left done %v", test.mode, todo.Done)
+		}
+		if got := todo.progress(); got != test.progress {
+			t.Errorf("progress after setDone with subtasks %s = %q, want %q", test.mode, got, test.progress)
+		}
+	}
+}
@@ -2,6 +2,8 @@ package main
 
 import (
 	"fmt"
+	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -9,165 +11,662 @@ import (
 	"github.com/rs/xid"
 )
 
-func addTodo(opts Opts, repo *TodoRepo) error {
+func addTodo(opts Opts, repo Repository) error {
 	todo := Todo{
-		ID:     xid.New().String(),
-		Title:  opts.params["title"].(string),
-		Due:    opts.params["due"].(time.Time),
-		Tags:   opts.params["tags"].([]string),
-		Done:   opts.params["done"].(bool),
-		Effort: opts.params["effort"].(float32),
+		ID:       xid.New().String(),
+		Title:    opts.params["title"].(string),
+		Due:      opts.params["due"].(time.Time),
+		Tags:     opts.params["tags"].([]string),
+		Done:     opts.params["done"].(bool),
+		Estimate: opts.params["estimate"].(float32),
+		Priority: opts.params["priority"].(Priority),
 	}
+
+	if refsi, present := opts.params["depends"]; present {
+		dependsOn, err := resolveDependencies(refsi.([]string), repo)
+		if err != nil {
+			return err
+		}
+		todo.DependsOn = dependsOn
+	}
+
+	if rulei, present := opts.params["recur"]; present {
+		err := setRecurrence(&todo, rulei.(string))
+		if err != nil {
+			return err
+		}
+	}
+
+	if contextsi, present := opts.params["contexts"]; present {
+		todo.Contexts = contextsi.([]string)
+	}
+	applyTagChanges(&todo, opts)
+
+	if projecti, present := opts.params["project"]; present {
+		todo.Project = projecti.(string)
+		err := ensureProject(todo.Project, repo)
+		if err != nil {
+			return err
+		}
+	}
+
 	return repo.CreateTodo(UserKey, todo)
 }
 
-func listTodos(opts Opts, repo *TodoRepo) error {
+func listTodos(opts Opts, repo Repository) error {
 	todos, err := getTodosByFilter(opts, repo)
 	if err != nil {
 		return err
 	}
 
-	listMapping := printTodos(opts, todos)
+	blocked, spent := map[string]bool{}, map[string]float32{}
+	if isPendingListing(opts) {
+		sortByPriority(todos)
+		blocked = blockedTodos(todos, repo)
+	} else {
+		spent, err = spentByTodo(todos, repo)
+		if err != nil {
+			return err
+		}
+	}
+
+	listMapping := printTodos(opts, todos, blocked, spent)
 	repo.SetListMapping(listMapping)
 
 	return nil
 }
 
-func showTodo(opts Opts, repo *TodoRepo) error {
-	id := idFromOpts(opts, repo)
+func showTodo(opts Opts, repo Repository) error {
+	id, err := idFromOpts(opts, repo)
+	if err != nil {
+		return err
+	}
 	todo, err := repo.GetTodo(UserKey, id)
 	if err != nil {
 		return err
 	}
-	printTodo(todo)
+	sessions, err := repo.GetSessions(UserKey, todo.ID)
+	if err != nil {
+		return err
+	}
+	printTodo(todo, openBlockers(todo, repo), sessions)
 	return nil
 }
 
-func setDone(opts Opts, repo *TodoRepo) error {
-	id := idFromOpts(opts, repo)
+func setDone(opts Opts, repo Repository) error {
+	id, err := idFromOpts(opts, repo)
+	if err != nil {
+		return err
+	}
 	status := opts.params["done"].(bool)
+
+	if status {
+		todo, err := repo.GetTodo(UserKey, id)
+		if err != nil {
+			return err
+		}
+
+		if blockers := openBlockers(todo, repo); len(blockers) > 0 {
+			fmt.Fprintf(os.Stderr, "Warning: %s is still blocked by %s\n", todo.Title, titles(blockers))
+		}
+	}
+
 	return repo.SetTodoDone(UserKey, id, status)
 }
 
-func setDue(opts Opts, repo *TodoRepo) error {
-	id := idFromOpts(opts, repo)
+func setDue(opts Opts, repo Repository) error {
+	id, err := idFromOpts(opts, repo)
+	if err != nil {
+		return err
+	}
 	due := opts.params["due"].(time.Time)
 	return repo.SetTodoDue(UserKey, id, due)
 }
 
-func setTags(opts Opts, repo *TodoRepo) error {
-	id := idFromOpts(opts, repo)
+func setTags(opts Opts, repo Repository) error {
+	id, err := idFromOpts(opts, repo)
+	if err != nil {
+		return err
+	}
 	tags := opts.params["tags"].([]string)
 	return repo.SetTodoTags(UserKey, id, tags)
 }
 
-func setEffort(opts Opts, repo *TodoRepo) error {
-	id := idFromOpts(opts, repo)
-	effort := opts.params["effort"].(float32)
-	return repo.SetTodoEffort(UserKey, id, effort)
+func setEstimate(opts Opts, repo Repository) error {
+	id, err := idFromOpts(opts, repo)
+	if err != nil {
+		return err
+	}
+	estimate := opts.params["estimate"].(float32)
+	return repo.SetTodoEstimate(UserKey, id, estimate)
 }
 
-func deleteTodo(opts Opts, repo *TodoRepo) error {
-	id := idFromOpts(opts, repo)
+func deleteTodo(opts Opts, repo Repository) error {
+	id, err := idFromOpts(opts, repo)
+	if err != nil {
+		return err
+	}
 	return repo.DeleteTodo(UserKey, id)
 }
 
-func updateTodo(opts Opts, repo *TodoRepo) error {
-	id := idFromOpts(opts, repo)
-	todo, err := repo.GetTodo(UserKey, id)
+func updateTodo(opts Opts, repo Repository) error {
+	id, err := idFromOpts(opts, repo)
+	if err != nil {
+		return err
+	}
+
+	var dependsOn []string
+	if refsi, present := opts.params["depends"]; present {
+		var err error
+		dependsOn, err = resolveDependencies(refsi.([]string), repo)
+		if err != nil {
+			return err
+		}
+
+		err = checkDependencies(id, dependsOn, repo)
+		if err != nil {
+			return err
+		}
+	}
+
+	if projecti, present := opts.params["project"]; present {
+		err := ensureProject(projecti.(string), repo)
+		if err != nil {
+			return err
+		}
+	}
+
+	return repo.MutateAndCreateTodo(UserKey, id, func(todo *Todo) (*Todo, error) {
+		var next *Todo
+
+		if dependsOn != nil {
+			todo.DependsOn = dependsOn
+		}
+
+		if rulei, present := opts.params["recur"]; present {
+			err := setRecurrence(todo, rulei.(string))
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if titlei, present := opts.params["title"]; present {
+			todo.Title = titlei.(string)
+		}
+
+		if donei, present := opts.params["done"]; present {
+			var err error
+			next, err = todo.markDone(donei.(bool))
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if duei, present := opts.params["due"]; present {
+			todo.Due = duei.(time.Time)
+		}
+
+		if estimatei, present := opts.params["estimate"]; present {
+			todo.Estimate = estimatei.(float32)
+		}
+
+		if projecti, present := opts.params["project"]; present {
+			todo.Project = projecti.(string)
+		}
+
+		if contextsi, present := opts.params["contexts"]; present {
+			todo.Contexts = contextsi.([]string)
+		}
+
+		if tagsi, present := opts.params["tags"]; present {
+			todo.Tags = tagsi.([]string)
+		}
+		applyTagChanges(todo, opts)
+
+		if priorityi, present := opts.params["priority"]; present {
+			todo.Priority = priorityi.(Priority)
+		}
+
+		return next, nil
+	})
+}
+
+func checkStore(opts Opts, repo Repository) error {
+	checker, ok := repo.(IndexChecker)
+	if !ok {
+		return fmt.Errorf("This store keeps no hand maintained indexes, nothing to check")
+	}
+
+	repair := opts.params["repair"].(bool)
+	problems, err := checker.CheckIndexes(repair)
 	if err != nil {
 		return err
 	}
 
-	if donei, present := opts.params["done"]; present {
-		todo.Done = donei.(bool)
+	for _, problem := range problems {
+		fmt.Printf("%s: %s %s\n", problem.UserID, problem.Kind, problem.Detail)
 	}
 
-	if duei, present := opts.params["due"]; present {
-		todo.Due = duei.(time.Time)
+	switch {
+	case len(problems) == 0:
+		fmt.Println("No problems found")
+	case repair:
+		fmt.Printf("%d problems found, indexes rebuilt\n", len(problems))
+	default:
+		return fmt.Errorf("%d problems found, run todo fsck --repair to rebuild the indexes", len(problems))
+	}
+
+	return nil
+}
+
+// addNote appends a note to the todo, the text is taken from the
+// command line or written in $EDITOR when none is given
+func addNote(opts Opts, repo Repository) error {
+	id, err := idFromOpts(opts, repo)
+	if err != nil {
+		return err
+	}
+	if _, err := repo.GetTodo(UserKey, id); err != nil {
+		return err
 	}
 
-	if efforti, present := opts.params["effort"]; present {
-		todo.Effort = efforti.(float32)
+	text, _ := opts.params["text"].(string)
+	if text == "" {
+		edited, err := runEditor([]byte{}, ".txt")
+		if err != nil {
+			return err
+		}
+		text = string(edited)
 	}
 
-	if tagsi, present := opts.params["tags"]; present {
-		todo.Tags = tagsi.([]string)
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return fmt.Errorf("Note is empty, nothing added")
 	}
 
-	return repo.UpdateTodo(UserKey, id, todo)
+	return repo.MutateTodo(UserKey, id, func(todo *Todo) error {
+		todo.Notes = append(todo.Notes, Note{Time: time.Now(), Text: text})
+		return nil
+	})
+}
+
+func addSubtask(opts Opts, repo Repository) error {
+	id, err := idFromOpts(opts, repo)
+	if err != nil {
+		return err
+	}
+	return repo.MutateTodo(UserKey, id, func(todo *Todo) error {
+		todo.Subtasks = append(todo.Subtasks, Subtask{Title: opts.params["title"].(string)})
+		return nil
+	})
 }
 
-func getTodosByFilter(opts Opts, repo *TodoRepo) ([]Todo, error) {
+func setSubtaskDone(opts Opts, repo Repository) error {
+	id, err := idFromOpts(opts, repo)
+	if err != nil {
+		return err
+	}
+	return repo.MutateTodo(UserKey, id, func(todo *Todo) error {
+		i, err := subtaskIndex(opts, *todo)
+		if err != nil {
+			return err
+		}
+
+		todo.Subtasks[i].Done = opts.params["done"].(bool)
+		return nil
+	})
+}
+
+func deleteSubtask(opts Opts, repo Repository) error {
+	id, err := idFromOpts(opts, repo)
+	if err != nil {
+		return err
+	}
+	return repo.MutateTodo(UserKey, id, func(todo *Todo) error {
+		i, err := subtaskIndex(opts, *todo)
+		if err != nil {
+			return err
+		}
+
+		todo.Subtasks = append(todo.Subtasks[:i], todo.Subtasks[i+1:]...)
+		return nil
+	})
+}
+
+// subtaskIndex returns the position in todo.Subtasks of the subtask
+// numbered from 1 in opts
+func subtaskIndex(opts Opts, todo Todo) (int, error) {
+	n := opts.params["subtask"].(int)
+	if n < 1 || n > len(todo.Subtasks) {
+		return 0, fmt.Errorf("%s has no subtask %d, it has %d", todo.Title, n, len(todo.Subtasks))
+	}
+	return n - 1, nil
+}
+
+// setRecurrence sets the recurrence rule of the todo, starting a series
+// with the todo as its first occurrence. An empty rule stops it
+func setRecurrence(todo *Todo, rule string) error {
+	if rule != "" {
+		if _, err := parseRecurrence(rule); err != nil {
+			return err
+		}
+
+		if todo.SeriesID == "" {
+			todo.SeriesID = todo.ID
+		}
+	}
+
+	todo.Recur = rule
+	return nil
+}
+
+// editSeries applies the given changes to every todo of the series the
+// todo belongs to, done ones included. Due dates and done state belong
+// to single occurrences and can't be changed this way
+func editSeries(opts Opts, repo Repository) error {
+	id, err := idFromOpts(opts, repo)
+	if err != nil {
+		return err
+	}
+	todo, err := repo.GetTodo(UserKey, id)
+	if err != nil {
+		return err
+	}
+	if todo.SeriesID == "" {
+		return fmt.Errorf("%s is not part of a recurring series", todo.Title)
+	}
+
+	for _, key := range []string{"due", "done"} {
+		if _, present := opts.params[key]; present {
+			return fmt.Errorf("The %s of a series can't be changed, change the single todo instead", key)
+		}
+	}
+
+	todos, err := repo.GetAllTodos(UserKey)
+	if err != nil {
+		return err
+	}
+
+	updated := 0
+	for _, member := range todos {
+		if member.SeriesID != todo.SeriesID {
+			continue
+		}
+
+		err = repo.MutateTodo(UserKey, member.ID, func(t *Todo) error {
+			if titlei, present := opts.params["title"]; present {
+				t.Title = titlei.(string)
+			}
+
+			if estimatei, present := opts.params["estimate"]; present {
+				t.Estimate = estimatei.(float32)
+			}
+
+			if tagsi, present := opts.params["tags"]; present {
+				t.Tags = tagsi.([]string)
+			}
+
+			if priorityi, present := opts.params["priority"]; present {
+				t.Priority = priorityi.(Priority)
+			}
+
+			if rulei, present := opts.params["recur"]; present {
+				return setRecurrence(t, rulei.(string))
+			}
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		updated++
+	}
+
+	fmt.Printf("%d todos of the series updated\n", updated)
+	return nil
+}
+
+func getTodosByFilter(opts Opts, repo Repository) ([]Todo, error) {
 	filter := opts.params["type"].(string)
 	switch filter {
 	case "pending":
-		return repo.GetPendingTodos(UserKey)
+		todos, err := repo.GetPendingTodos(UserKey)
+		if err != nil {
+			return nil, err
+		}
+
+		context, _ := opts.params["context"].(string)
+		filtered := []Todo{}
+		for _, todo := range todos {
+			if todo.inContext(context) {
+				filtered = append(filtered, todo)
+			}
+		}
+		return filtered, nil
 	case "bydate":
 		return repo.GetTodosByDate(UserKey, opts.params["date"].(time.Time))
+	case "byweek":
+		start := opts.params["date"].(time.Time)
+		return repo.GetTodosInRange(UserKey, start, start.AddDate(0, 0, 6))
+	case "byrange":
+		return repo.GetTodosInRange(UserKey, opts.params["from"].(time.Time), opts.params["to"].(time.Time))
+	case "completed":
+		return repo.GetTodosCompletedInRange(UserKey, opts.params["from"].(time.Time), opts.params["to"].(time.Time))
+	case "blocked", "ready":
+		todos, err := repo.GetPendingTodos(UserKey)
+		if err != nil {
+			return nil, err
+		}
+
+		blocked := blockedTodos(todos, repo)
+		filtered := []Todo{}
+		for _, todo := range todos {
+			if blocked[todo.ID] == (filter == "blocked") {
+				filtered = append(filtered, todo)
+			}
+		}
+		return filtered, nil
+	case "overdue":
+		return overdueTodos(repo)
+	case "bytags":
+		return todosByTags(opts.params["tags"].([][]string), repo)
+	case "search":
+		return searchTodos(opts.params["terms"].([]searchTerm), repo)
 	default:
 		return nil, fmt.Errorf("Unknow option for type %s", filter)
 	}
 }
 
-func printTodos(opts Opts, todos []Todo) map[string]string {
-	heading := getHeadingForPrint(opts)
+// isPendingListing tells if the listing shows pending todos only, those
+// are printed with their due date instead of done state and estimate
+func isPendingListing(opts Opts) bool {
+	filter := opts.params["type"].(string)
+	return filter == "pending" || filter == "blocked" || filter == "ready" || filter == "overdue"
+}
+
+func printTodos(opts Opts, todos []Todo, blocked map[string]bool, hours map[string]float32) map[string]string {
+	heading, names := getHeadingForPrint(opts)
+	heading = strings.TrimSpace(strings.Title(heading) + " " + names)
 
-	fmt.Printf("\n%s\n", strings.Title(heading))
+	fmt.Printf("\n%s\n", heading)
 	for range heading {
 		fmt.Print("-")
 	}
 	fmt.Println()
+
+	filter := opts.params["type"].(string)
+	if filter == "byweek" || filter == "byrange" {
+		return printTodosByDay(todos, hours)
+	}
+
 	mapping := map[string]string{}
-	totalEffort := float32(0.0)
+	planned, spent := float32(0.0), float32(0.0)
 	completedTodos := 0
 
 	for i, todo := range todos {
 		s := strconv.Itoa(i + 1)
 		mapping[s] = todo.ID
-		if heading == "Pending" {
-			fmt.Printf("%s. %s - %s\n", s, todo.Due.Format("02 Jan"), todo.Title)
+		if isPendingListing(opts) {
+			mark := todo.Priority.mark() + todo.progress()
+			if blocked[todo.ID] {
+				mark = "(blocked) " + mark
+			}
+			fmt.Printf("%s. %s - %s%s\n", s, todo.duestr(), mark, todo.Title)
 			continue
 		}
 
-		if todo.Done {
-			fmt.Printf("%s. [X] (%0.1f) %s\n", s, todo.Effort, todo.Title)
-		} else {
-			fmt.Printf("%s. [ ] (%0.1f) %s\n", s, todo.Effort, todo.Title)
-		}
+		printTodoLine(s, todo)
 
-		totalEffort += todo.Effort
+		planned += todo.Estimate
+		spent += hours[todo.ID]
 		if todo.Done {
 			completedTodos++
 		}
 	}
 	fmt.Println()
 
-	if heading != "Pending" {
+	if !isPendingListing(opts) {
 		fmt.Printf("%d / %d Todos pending\n", len(todos)-completedTodos, len(todos))
-		fmt.Printf("%.1f hours of total effort\n\n", totalEffort)
+		fmt.Printf("%.1fh planned / %.1fh spent\n\n", planned, spent)
+	}
+
+	return mapping
+}
+
+// printTodosByDay prints the todos grouped under their due date along
+// with the pending count and the planned and spent hours of each day.
+// The todos are expected to be ordered by due date
+func printTodosByDay(todos []Todo, hours map[string]float32) map[string]string {
+	mapping := map[string]string{}
+	planned, spent := float32(0.0), float32(0.0)
+	completedTodos := 0
+
+	index := 0
+	for start := 0; start < len(todos); {
+		end := start
+		for end < len(todos) && string(todos[end].due()) == string(todos[start].due()) {
+			end++
+		}
+
+		day := todos[start:end]
+		dayPlanned, daySpent := float32(0.0), float32(0.0)
+		dayCompleted := 0
+
+		fmt.Printf("\n%s\n", day[0].dueLocal().Format("Mon 02 Jan"))
+		for _, todo := range day {
+			index++
+			s := strconv.Itoa(index)
+			mapping[s] = todo.ID
+			printTodoLine(s, todo)
+
+			dayPlanned += todo.Estimate
+			daySpent += hours[todo.ID]
+			if todo.Done {
+				dayCompleted++
+			}
+		}
+		fmt.Printf("   %d / %d pending, %.1fh planned / %.1fh spent\n", len(day)-dayCompleted, len(day), dayPlanned, daySpent)
+
+		planned += dayPlanned
+		spent += daySpent
+		completedTodos += dayCompleted
+		start = end
 	}
+	fmt.Println()
+
+	fmt.Printf("%d / %d Todos pending\n", len(todos)-completedTodos, len(todos))
+	fmt.Printf("%.1fh planned / %.1fh spent\n\n", planned, spent)
 
 	return mapping
 }
 
-func getHeadingForPrint(opts Opts) string {
+func printTodoLine(s string, todo Todo) {
+	mark := todo.Priority.mark() + todo.progress()
+	if todo.hasTime() {
+		mark = todo.dueLocal().Format("15:04 ") + mark
+	}
+
+	if todo.Done {
+		fmt.Printf("%s. [X] (%0.1f) %s%s\n", s, todo.Estimate, mark, todo.Title)
+	} else {
+		fmt.Printf("%s. [ ] (%0.1f) %s%s\n", s, todo.Estimate, mark, todo.Title)
+	}
+}
+
+// sortByPriority orders the todos by priority, highest first, and by
+// due date within the same priority
+func sortByPriority(todos []Todo) {
+	sort.SliceStable(todos, func(i, j int) bool {
+		if todos[i].Priority != todos[j].Priority {
+			return todos[i].Priority > todos[j].Priority
+		}
+		return todos[i].Due.Before(todos[j].Due)
+	})
+}
+
+// getHeadingForPrint returns the heading of a listing and the context,
+// tags or search terms it is about. Only the heading is title cased, the
+// names are printed as they were given
+func getHeadingForPrint(opts Opts) (string, string) {
 	filter := opts.params["type"].(string)
 	switch filter {
 	case "pending":
-		return "Pending"
+		if context, _ := opts.params["context"].(string); context != "" {
+			return "Pending", "@" + context
+		}
+		return "Pending", ""
+	case "blocked":
+		return "Blocked", ""
+	case "ready":
+		return "Ready", ""
+	case "project":
+		return "Todos", ""
+	case "search":
+		terms := []string{}
+		for _, term := range opts.params["terms"].([]searchTerm) {
+			terms = append(terms, term.String())
+		}
+		return "Search", strings.Join(terms, " ")
+	case "bytags":
+		groups := []string{}
+		for _, tags := range opts.params["tags"].([][]string) {
+			groups = append(groups, "#"+strings.Join(tags, ",#"))
+		}
+		return "", strings.Join(groups, " ")
+	case "overdue":
+		return "Overdue", ""
 	case "bydate":
 		date := opts.params["date"].(time.Time)
-		return date.Format("2006-01-02")
+		return date.Format("2006-01-02"), ""
+	case "byweek":
+		start := opts.params["date"].(time.Time)
+		end := start.AddDate(0, 0, 6)
+		return start.Format("02 Jan") + " - " + end.Format("02 Jan 2006"), ""
+	case "byrange":
+		from := opts.params["from"].(time.Time)
+		to := opts.params["to"].(time.Time)
+		return from.Format("2006-01-02") + " .. " + to.Format("2006-01-02"), ""
+	case "completed":
+		from := opts.params["from"].(time.Time)
+		to := opts.params["to"].(time.Time)
+		if from.Equal(to) {
+			return "Completed " + from.Format("2006-01-02"), ""
+		}
+		return "Completed " + from.Format("2006-01-02") + " .. " + to.Format("2006-01-02"), ""
 	default:
-		return "Unknown"
+		return "Unknown", ""
 	}
 }
 
-func idFromOpts(opts Opts, repo *TodoRepo) string {
+// idFromOpts returns the ID of the todo listed under the index given in
+// the opts by the last listing
+func idFromOpts(opts Opts, repo Repository) (string, error) {
 	key := opts.params["id"].(string)
 	listMapping := repo.GetListMapping()
-	id := listMapping[key]
+	id, ok := listMapping[key]
+	if !ok {
+		return "", fmt.Errorf("No todo listed as %s", key)
+	}
 
-	return id
+	return id, nil
 }
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// runCommands runs command lines against repo as todo would, the todo
+// listed as 1 being todo
+func runCommands(t *testing.T, repo Repository, todo Todo, commands ...string) error {
+	t.Helper()
+
+	repo.SetListMapping(map[string]string{"1": todo.ID})
+	for _, command := range commands {
+		opts, err := getOpts(strings.Fields(command))
+		if err != nil {
+			return err
+		}
+
+		err = OperationMap[opts.option](opts, repo)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func TestSortByPriority(t *testing.T) {
+	later := newTestTodo("later", today().AddDate(0, 0, 1))
+	later.Priority = PriorityHigh
+	urgent := newTestTodo("urgent", today())
+	urgent.Priority = PriorityHigh
+	low := newTestTodo("low", today().AddDate(0, 0, -1))
+	low.Priority = PriorityLow
+	none := newTestTodo("none", today().AddDate(0, 0, -2))
+
+	todos := []Todo{none, low, later, urgent}
+	sortByPriority(todos)
+
+	got := []string{}
+	for _, todo := range todos {
+		got = append(got, todo.Title)
+	}
+	if want := []string{"urgent", "later", "low", "none"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("sortByPriority = %v, want %v", got, want)
+	}
+}
+
+func TestSubtasks(t *testing.T) {
+	repo := newMemoryRepo()
+	todo := newTestTodo("Move house", today())
+	createTodos(t, repo, todo)
+
+	err := runCommands(t, repo, todo,
+		"todo 1 sub pack boxes", "todo 1 sub clean up", "todo 1 sub book a van",
+		"todo 1.1 done", "todo 1.2 delete")
+	if err != nil {
+		t.Fatalf("subtask commands: %v", err)
+	}
+
+	got, _ := repo.GetTodo(UserKey, todo.ID)
+	want := []Subtask{{Title: "pack boxes", Done: true}, {Title: "book a van"}}
+	if !reflect.DeepEqual(got.Subtasks, want) {
+		t.Errorf("subtasks = %+v, want %+v", got.Subtasks, want)
+	}
+
+	if err = runCommands(t, repo, todo, "todo 1.3 done"); err == nil {
+		t.Errorf("marking subtask 3 of 2 done, want an error")
+	}
+}
+
+func TestIndexNotListed(t *testing.T) {
+	repo := newMemoryRepo()
+	todo := newTestTodo("Paint the fence", today())
+	createTodos(t, repo, todo)
+
+	err := runCommands(t, repo, todo, "todo 3 done")
+	if err == nil || err.Error() != "No todo listed as 3" {
+		t.Errorf("marking a todo not in the last listing done returned %v, want No todo listed as 3", err)
+	}
+}
@@ -14,6 +14,32 @@ type Opts struct {
 	params map[string]interface{}
 }
 
+// getStoreSpec takes the --db flag out of args and returns the store
+// it names along with the remaining args. Without the flag the store
+// from the config is returned
+func getStoreSpec(args []string, store string) (string, []string, error) {
+	rest := []string{}
+	for i := 0; i < len(args); i++ {
+		if strings.HasPrefix(args[i], "--db=") {
+			store = strings.TrimPrefix(args[i], "--db=")
+			continue
+		}
+
+		if args[i] == "--db" {
+			if i+1 == len(args) {
+				return "", nil, fmt.Errorf("Missing value for --db, expected bolt:<path> or sqlite:<path>")
+			}
+			store = args[i+1]
+			i++
+			continue
+		}
+
+		rest = append(rest, args[i])
+	}
+
+	return store, rest, nil
+}
+
 func getOpts(args []string) (Opts, error) {
 	var opts Opts
 	opts.params = map[string]interface{}{}
@@ -21,6 +47,94 @@ func getOpts(args []string) (Opts, error) {
 	if len(args) == 1 {
 		opts.option = ListTodos
 		opts.params["type"] = "pending"
+		opts.params["context"] = config.Context
+		return opts, nil
+	}
+
+	// todo context [@name | none], longer lines starting with context
+	// are todos like "context switch cleanup"
+	if args[1] == "context" && len(args) <= 3 {
+		opts.option = SetContext
+		if len(args) == 3 {
+			isContexts, contexts := isContexts("@" + strings.TrimPrefix(args[2], "@"))
+			if !isContexts || len(contexts) > 1 {
+				return Opts{}, fmt.Errorf("Invalid context %s, expected a name like @office or none", args[2])
+			}
+			opts.params["context"] = strings.Join(contexts, "")
+		}
+		return opts, nil
+	}
+
+	if args[1] == "migrate" && len(args) > 2 && (args[2] == "--from" || args[2] == "--to") {
+		return getMigrateOpts(args[2:])
+	}
+
+	// todo done <date or range> lists what was completed, anything else
+	// starting with done is left for the other commands, like adding a
+	// todo titled "done laundry"
+	if args[1] == "done" {
+		if completedOpts, err := getCompletedOpts(args[2:]); err == nil {
+			return completedOpts, nil
+		}
+	}
+
+	// todo project <name> [action] is a project command and reports its
+	// errors, a longer line without a project action is left for adding
+	// todos like "project kickoff prep"
+	if args[1] == "project" {
+		projectOpts, err := getProjectOpts(args[2:])
+		if err == nil || len(args) <= 3 || isProjectAction(args[2], args[3]) {
+			return projectOpts, err
+		}
+	}
+
+	if args[1] == "projects" && len(args) == 2 {
+		opts.option = ListProjects
+		return opts, nil
+	}
+
+	// todo search <words> is always a search, todos starting with the
+	// word search need another first word
+	if args[1] == "search" {
+		terms, err := parseSearchQuery(args[2:])
+		if err != nil {
+			return Opts{}, err
+		}
+
+		opts.option = ListTodos
+		opts.params["type"] = "search"
+		opts.params["terms"] = terms
+		return opts, nil
+	}
+
+	if args[1] == "tag" && len(args) > 2 && (args[2] == "rename" || args[2] == "merge") {
+		return getTagOpts(args[2:])
+	}
+
+	if len(args) >= 3 && indexListRe.MatchString(args[1]) && areTagChanges(args[2:]) {
+		opts.option = ChangeTags
+		opts.params["ids"] = strings.Split(args[1], ",")
+		for _, arg := range args[2:] {
+			fillInTagChange(arg, &opts)
+		}
+		return opts, nil
+	}
+
+	if args[1] == "tags" && len(args) == 2 {
+		opts.option = ShowTags
+		return opts, nil
+	}
+
+	if isTagQuery, query := isTagQuery(args[1:]); isTagQuery {
+		opts.option = ListTodos
+		opts.params["type"] = "bytags"
+		opts.params["tags"] = query
+		return opts, nil
+	}
+
+	if args[1] == "fsck" && (len(args) == 2 || (len(args) == 3 && args[2] == "--repair")) {
+		opts.option = CheckStore
+		opts.params["repair"] = len(args) == 3
 		return opts, nil
 	}
 
@@ -31,23 +145,102 @@ func getOpts(args []string) (Opts, error) {
 			return opts, nil
 		}
 
-		match, _ = regexp.MatchString(`^(today|tomorrow|yesterday|\d\d\d\d-\d\d-\d\d)$`, args[1])
-		if match {
+		if isDate, date := isDate(args[1]); isDate {
 			opts.option = ListTodos
 			opts.params["type"] = "bydate"
-			opts.params["date"] = toDate(args[1])
+			opts.params["date"] = date
+			return opts, nil
+		}
+
+		if dates := strings.Split(args[1], ".."); len(dates) == 2 {
+			from, err := toDate(dates[0])
+			if err != nil {
+				return Opts{}, err
+			}
+			to, err := toDate(dates[1])
+			if err != nil {
+				return Opts{}, err
+			}
+			if to.Before(from) {
+				return Opts{}, fmt.Errorf("Invalid date range %s, end date is before start date", args[1])
+			}
+
+			opts.option = ListTodos
+			opts.params["type"] = "byrange"
+			opts.params["from"] = from
+			opts.params["to"] = to
+			return opts, nil
+		}
+
+		if isContexts, contexts := isContexts(args[1]); isContexts && len(contexts) == 1 {
+			opts.option = ListTodos
+			opts.params["type"] = "pending"
+			opts.params["context"] = contexts[0]
+			return opts, nil
+		}
+
+		if args[1] == "overdue" {
+			opts.option = ListTodos
+			opts.params["type"] = "overdue"
+			return opts, nil
+		}
+
+		if args[1] == "rollover" {
+			opts.option = RolloverTodos
+			return opts, nil
+		}
+
+		if args[1] == "stop" {
+			opts.option = StopTimer
+			return opts, nil
+		}
+
+		if args[1] == "blocked" || args[1] == "ready" {
+			opts.option = ListTodos
+			opts.params["type"] = args[1]
 			return opts, nil
 		}
 
 		if args[1] == "thisweek" || args[1] == "lastweek" || args[1] == "nextweek" {
-			panic("not implemented")
+			opts.option = ListTodos
+			opts.params["type"] = "byweek"
+			opts.params["date"] = toWeek(args[1])
+			return opts, nil
 		}
 
 		opts.option = ShowTodoDetail
 		opts.params["id"] = args[1]
 	}
 
+	if len(args) >= 3 {
+		if isDate, date := isDate(strings.Join(args[1:], " ")); isDate {
+			opts.option = ListTodos
+			opts.params["type"] = "bydate"
+			opts.params["date"] = date
+			return opts, nil
+		}
+	}
+
 	if len(args) == 3 {
+		if subtask := regexp.MustCompile(`^(\d+)\.(\d+)$`).FindStringSubmatch(args[1]); subtask != nil {
+			opts.params["id"] = subtask[1]
+			opts.params["subtask"], _ = strconv.Atoi(subtask[2])
+
+			switch args[2] {
+			case "done":
+				opts.option = SetSubtaskDone
+				opts.params["done"] = true
+			case "pending":
+				opts.option = SetSubtaskDone
+				opts.params["done"] = false
+			case "delete":
+				opts.option = DeleteSubtask
+			default:
+				return Opts{}, fmt.Errorf("Unknown subtask action %s, expected done, pending or delete", args[2])
+			}
+			return opts, nil
+		}
+
 		opts.params["id"] = args[1]
 
 		if args[2] == "delete" {
@@ -55,6 +248,51 @@ func getOpts(args []string) (Opts, error) {
 			return opts, nil
 		}
 
+		if isPriority, priority := isPriority(args[2]); isPriority {
+			opts.option = UpdateTodo
+			opts.params["priority"] = priority
+			return opts, nil
+		}
+
+		if isProject, project := isProject(args[2]); isProject {
+			opts.option = UpdateTodo
+			opts.params["project"] = project
+			return opts, nil
+		}
+
+		if isContexts, contexts := isContexts(args[2]); isContexts {
+			opts.option = UpdateTodo
+			opts.params["contexts"] = contexts
+			return opts, nil
+		}
+
+		if isDependencies, refs := isDependencies(args[2]); isDependencies {
+			opts.option = UpdateTodo
+			opts.params["depends"] = refs
+			return opts, nil
+		}
+
+		if isRecurrence, rule := isRecurrence(args[2]); isRecurrence {
+			opts.option = UpdateTodo
+			opts.params["recur"] = rule
+			return opts, nil
+		}
+
+		if args[2] == "edit" {
+			opts.option = EditTodo
+			return opts, nil
+		}
+
+		if args[2] == "start" {
+			opts.option = StartTimer
+			return opts, nil
+		}
+
+		if args[2] == "note" {
+			opts.option = AddNote
+			return opts, nil
+		}
+
 		if args[2] == "done" {
 			opts.option = SetDone
 			opts.params["done"] = true
@@ -67,10 +305,9 @@ func getOpts(args []string) (Opts, error) {
 			return opts, nil
 		}
 
-		match, _ := regexp.MatchString(`^(today|tomorrow|yesterday|\d\d\d\d-\d\d-\d\d)$`, args[2])
-		if match {
+		if isDate, date := isDate(args[2]); isDate {
 			opts.option = SetDue
-			opts.params["due"] = toDate(args[2])
+			opts.params["due"] = date
 			return opts, nil
 		}
 
@@ -89,10 +326,10 @@ func getOpts(args []string) (Opts, error) {
 			return opts, nil
 		}
 
-		effort, err := strconv.ParseFloat(args[2], 32)
+		estimate, err := strconv.ParseFloat(args[2], 32)
 		if err == nil {
-			opts.option = SetEffort
-			opts.params["effort"] = float32(effort)
+			opts.option = SetEstimate
+			opts.params["estimate"] = float32(estimate)
 			return opts, nil
 		}
 
@@ -100,11 +337,43 @@ func getOpts(args []string) (Opts, error) {
 	}
 
 	if len(args) > 3 {
+		if isIndex, index := isIndex(args[1]); isIndex && args[2] == "note" {
+			opts.option = AddNote
+			opts.params["id"] = index
+			opts.params["text"] = strings.Join(args[3:], " ")
+			return opts, nil
+		}
+
+		if isIndex, index := isIndex(args[1]); isIndex && args[2] == "sub" {
+			opts.option = AddSubtask
+			opts.params["id"] = index
+			opts.params["title"] = strings.Join(args[3:], " ")
+			return opts, nil
+		}
+
+		if isIndex, index := isIndex(args[1]); isIndex && args[2] == "series" {
+			opts.option = EditSeries
+			opts.params["id"] = index
+			if len(args) == 4 && args[3] == "stop" {
+				opts.params["recur"] = ""
+				return opts, nil
+			}
+
+			err := fillInParams(args[3:], &opts)
+			if err != nil {
+				return Opts{}, err
+			}
+			return opts, nil
+		}
+
 		if isIndex, index := isIndex(args[1]); isIndex {
-			params := args[1:]
+			params := args[2:]
 			opts.option = UpdateTodo
 			opts.params["id"] = index
-			fillInParams(params, &opts)
+			err := fillInParams(params, &opts)
+			if err != nil {
+				return Opts{}, err
+			}
 			return opts, nil
 		}
 	}
@@ -129,42 +398,221 @@ func getOpts(args []string) (Opts, error) {
 		opts.params["title"] = strings.Join(temp, " ")
 		opts.params["due"] = today()
 		opts.params["done"] = false
-		opts.params["effort"] = float32(0.0)
+		opts.params["estimate"] = float32(0.0)
 		opts.params["tags"] = []string{}
-		fillInParams(params, &opts)
+		opts.params["priority"] = PriorityNone
+		err := fillInParams(params, &opts)
+		if err != nil {
+			return Opts{}, err
+		}
 		return opts, nil
 	}
 
 	return opts, nil
 }
 
-func fillInParams(params []string, opts *Opts) {
-	for _, param := range params {
-		fillInParam(param, opts)
+// getCompletedOpts reads what todo done lists, the todos completed on
+// a day like "yesterday" or in a range like "2026-10-01..today". With no
+// date it lists what was completed today
+func getCompletedOpts(args []string) (Opts, error) {
+	var opts Opts
+	opts.option = ListTodos
+	opts.params = map[string]interface{}{}
+	opts.params["type"] = "completed"
+
+	when := strings.Join(args, " ")
+	if when == "" {
+		when = "today"
+	}
+
+	dates := strings.Split(when, "..")
+	from, err := toDate(dates[0])
+	if err != nil {
+		return Opts{}, err
+	}
+
+	to := from
+	if len(dates) == 2 {
+		to, err = toDate(dates[1])
+		if err != nil {
+			return Opts{}, err
+		}
+	}
+	if len(dates) > 2 || to.Before(from) {
+		return Opts{}, fmt.Errorf("Invalid date range %s", when)
+	}
+
+	opts.params["from"] = from
+	opts.params["to"] = to
+	return opts, nil
+}
+
+func getMigrateOpts(args []string) (Opts, error) {
+	var opts Opts
+	opts.option = MigrateStore
+	opts.params = map[string]interface{}{}
+
+	for i := 0; i+1 < len(args); i += 2 {
+		switch args[i] {
+		case "--from":
+			opts.params["from"] = args[i+1]
+		case "--to":
+			opts.params["to"] = args[i+1]
+		default:
+			return Opts{}, fmt.Errorf("Unknown argument for migrate: %s", args[i])
+		}
+	}
+
+	if opts.params["from"] == nil || opts.params["to"] == nil || len(args)%2 != 0 {
+		return Opts{}, fmt.Errorf("Usage: todo migrate --from bolt:<path> --to sqlite:<path>")
 	}
+
+	return opts, nil
 }
 
-func fillInParam(param string, opts *Opts) {
+// fillInParams sets the params given after the todo title or index.
+// Dates can span several words, like "next friday" or "in 2 weeks", so
+// the longest run of words that reads as a date is taken first
+func fillInParams(params []string, opts *Opts) error {
+	for i := 0; i < len(params); i++ {
+		if isFlag(params[i]) {
+			if i+1 == len(params) {
+				return fmt.Errorf("Missing value for %s", params[i])
+			}
+
+			err := fillInFlag(params[i], params[i+1], opts)
+			if err != nil {
+				return err
+			}
+			i++
+			continue
+		}
+
+		found := false
+		for n := 3; n > 1 && !found; n-- {
+			if i+n > len(params) {
+				continue
+			}
+
+			if isDate, date := isDate(strings.Join(params[i:i+n], " ")); isDate {
+				opts.params["due"] = date
+				i += n - 1
+				found = true
+			}
+		}
+
+		if found {
+			continue
+		}
+
+		if !fillInParam(params[i], opts) {
+			return fmt.Errorf("Unknown date or parameter: %s", params[i])
+		}
+	}
+
+	return nil
+}
+
+func fillInParam(param string, opts *Opts) bool {
 	if isDone, done := isDone(param); isDone {
 		opts.params["done"] = done
-		return
-
+		return true
 	}
 
 	if isDate, date := isDate(param); isDate {
 		opts.params["due"] = date
-		return
+		return true
 	}
 
 	if isEffort, effort := isEffort(param); isEffort {
-		opts.params["effort"] = float32(effort)
-		return
+		opts.params["estimate"] = float32(effort)
+		return true
 	}
 
 	if isTags, tags := isTags(param); isTags {
 		opts.params["tags"] = tags
-		return
+		return true
 	}
+
+	if isPriority, priority := isPriority(param); isPriority {
+		opts.params["priority"] = priority
+		return true
+	}
+
+	if isProject, project := isProject(param); isProject {
+		opts.params["project"] = project
+		return true
+	}
+
+	if fillInTagChange(param, opts) {
+		return true
+	}
+
+	if isContexts, contexts := isContexts(param); isContexts {
+		opts.params["contexts"] = contexts
+		return true
+	}
+
+	if isDependencies, refs := isDependencies(param); isDependencies {
+		opts.params["depends"] = refs
+		return true
+	}
+
+	if isRecurrence, rule := isRecurrence(param); isRecurrence {
+		opts.params["recur"] = rule
+		return true
+	}
+
+	return false
+}
+
+// fillInFlag sets the field named by flag, like -t "new title". Flags
+// let any field be given without relying on how the value looks
+func fillInFlag(flag string, value string, opts *Opts) error {
+	switch flag {
+	case "-t", "--title":
+		if strings.TrimSpace(value) == "" {
+			return fmt.Errorf("Title can not be empty")
+		}
+		opts.params["title"] = value
+	case "-d", "--due":
+		date, err := toDate(value)
+		if err != nil {
+			return err
+		}
+		opts.params["due"] = date
+	case "-e", "--effort", "--estimate":
+		estimate, err := strconv.ParseFloat(value, 32)
+		if err != nil {
+			return fmt.Errorf("Invalid effort %s, expected hours like 1.5", value)
+		}
+		opts.params["estimate"] = float32(estimate)
+	case "-P", "--priority":
+		priority, err := toPriority(value)
+		if err != nil {
+			return err
+		}
+		opts.params["priority"] = priority
+	case "-g", "--tags":
+		tags := []string{}
+		for _, tag := range strings.Split(value, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "#")
+			if tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+		opts.params["tags"] = tags
+	}
+
+	return nil
+}
+
+func isFlag(param string) bool {
+	switch param {
+	case "-t", "--title", "-d", "--due", "-e", "--effort", "--estimate", "-g", "--tags", "-P", "--priority":
+		return true
+	}
+	return false
 }
 
 func isIndex(param string) (bool, string) {
@@ -185,9 +633,9 @@ func isDone(param string) (bool, bool) {
 }
 
 func isDate(param string) (bool, time.Time) {
-	match, _ := regexp.MatchString(`^(today|tomorrow|yesterday|\d\d\d\d-\d\d-\d\d)$`, param)
-	if match {
-		return true, toDate(param)
+	date, err := toDate(param)
+	if err == nil {
+		return true, date
 	}
 	return false, time.Now()
 }
@@ -217,3 +665,67 @@ func isTags(param string) (bool, []string) {
 
 	return false, nil
 }
+
+// isTagQuery reads the args of a tag listing, every arg is a tag or a
+// comma separated list of tags like #work,#urgent. A todo matches when
+// it has all of the args and, within an arg, any of the tags
+func isTagQuery(args []string) (bool, [][]string) {
+	query := [][]string{}
+	for _, arg := range args {
+		isTags, tags := isTags(arg)
+		if !isTags {
+			return false, nil
+		}
+
+		for _, tag := range tags {
+			if tag == "" || strings.HasPrefix(tag, "#") {
+				return false, nil
+			}
+		}
+
+		query = append(query, tags)
+	}
+
+	return len(query) > 0, query
+}
+
+func isPriority(param string) (bool, Priority) {
+	if strings.HasPrefix(param, "!") && len(param) > 1 {
+		priority, err := toPriority(param[1:])
+		if err == nil {
+			return true, priority
+		}
+	}
+
+	return false, PriorityNone
+}
+
+// isDependencies reads after:1,2 as depending on the todos listed as 1
+// and 2, todo IDs can be given too. after:none clears the dependencies
+func isDependencies(param string) (bool, []string) {
+	if !strings.HasPrefix(param, "after:") || param == "after:" {
+		return false, nil
+	}
+
+	refs := []string{}
+	if param != "after:none" {
+		refs = strings.Split(strings.TrimPrefix(param, "after:"), ",")
+	}
+
+	return true, refs
+}
+
+// isRecurrence reads recur:<rule>, like recur:weekly:mon,thu, the rule
+// is checked when it is applied. recur:none stops the recurrence
+func isRecurrence(param string) (bool, string) {
+	if !strings.HasPrefix(param, "recur:") || param == "recur:" {
+		return false, ""
+	}
+
+	rule := strings.TrimPrefix(param, "recur:")
+	if rule == "none" {
+		rule = ""
+	}
+
+	return true, rule
+}
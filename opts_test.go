@@ -0,0 +1,113 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGetOpts(t *testing.T) {
+	tests := []struct {
+		args   string
+		option OpType
+		params map[string]interface{}
+	}{
+		// listings
+		{"todo", ListTodos, map[string]interface{}{"type": "pending"}},
+		{"todo today", ListTodos, map[string]interface{}{"type": "bydate", "date": today()}},
+		{"todo thisweek", ListTodos, map[string]interface{}{"type": "byweek"}},
+		{"todo overdue", ListTodos, map[string]interface{}{"type": "overdue"}},
+		{"todo blocked", ListTodos, map[string]interface{}{"type": "blocked"}},
+		{"todo #work #home,#errands", ListTodos, map[string]interface{}{
+			"type": "bytags", "tags": [][]string{{"work"}, {"home", "errands"}}}},
+		{"todo @phone", ListTodos, map[string]interface{}{"type": "pending", "context": "phone"}},
+		{"todo done", ListTodos, map[string]interface{}{"type": "completed", "from": today()}},
+		{"todo search new flat", ListTodos, map[string]interface{}{"type": "search"}},
+		{"todo projects", ListProjects, nil},
+		{"todo project kickoff", ShowProject, map[string]interface{}{"project": "kickoff"}},
+
+		// adding
+		{"todo buy milk today", AddTodo, map[string]interface{}{
+			"title": "Buy milk today", "due": today(), "tags": []string{}}},
+		{"todo buy milk -p tomorrow #home 1.5 !high", AddTodo, map[string]interface{}{
+			"title": "Buy milk", "due": today().AddDate(0, 0, 1), "tags": []string{"home"},
+			"estimate": float32(1.5), "priority": PriorityHigh}},
+		{"todo buy milk -p -t milk", AddTodo, map[string]interface{}{"title": "milk"}},
+
+		// updating
+		{"todo 1", ShowTodoDetail, map[string]interface{}{"id": "1"}},
+		{"todo 1 done", SetDone, map[string]interface{}{"id": "1", "done": true}},
+		{"todo 1 pending", SetDone, map[string]interface{}{"id": "1", "done": false}},
+		{"todo 1 tomorrow", SetDue, map[string]interface{}{"id": "1", "due": today().AddDate(0, 0, 1)}},
+		{"todo 1 #work,#home", SetTags, map[string]interface{}{"id": "1", "tags": []string{"work", "home"}}},
+		{"todo 1 2.5", SetEstimate, map[string]interface{}{"id": "1", "estimate": float32(2.5)}},
+		{"todo 1 delete", DeleteTodo, map[string]interface{}{"id": "1"}},
+		{"todo 1 edit", EditTodo, map[string]interface{}{"id": "1"}},
+		{"todo 1 !low", UpdateTodo, map[string]interface{}{"id": "1", "priority": PriorityLow}},
+		{"todo 1 +kickoff", UpdateTodo, map[string]interface{}{"id": "1", "project": "kickoff"}},
+		{"todo 1 @phone", UpdateTodo, map[string]interface{}{"id": "1", "contexts": []string{"phone"}}},
+		{"todo 1 after:2,3", UpdateTodo, map[string]interface{}{"id": "1", "depends": []string{"2", "3"}}},
+		{"todo 1 tomorrow 2 #work", UpdateTodo, map[string]interface{}{
+			"id": "1", "due": today().AddDate(0, 0, 1), "estimate": float32(2), "tags": []string{"work"}}},
+		{"todo 1 note call back first", AddNote, map[string]interface{}{"id": "1", "text": "call back first"}},
+		{"todo 1 sub pack boxes", AddSubtask, map[string]interface{}{"id": "1", "title": "pack boxes"}},
+		{"todo 1.2 done", SetSubtaskDone, map[string]interface{}{"id": "1", "subtask": 2, "done": true}},
+		{"todo 1,2 +#work -#home", ChangeTags, map[string]interface{}{"ids": []string{"1", "2"}}},
+		{"todo 1 start", StartTimer, map[string]interface{}{"id": "1"}},
+		{"todo stop", StopTimer, nil},
+
+		// commands and todos starting with the same word
+		{"todo done laundry today", AddTodo, map[string]interface{}{"title": "Done laundry today"}},
+		{"todo project kickoff prep -p mon", AddTodo, map[string]interface{}{"title": "Project kickoff prep"}},
+		{"todo project kickoff status hold", UpdateProject, map[string]interface{}{"project": "kickoff", "status": "hold"}},
+		{"todo context @office", SetContext, map[string]interface{}{"context": "office"}},
+		{"todo context switch cleanup", AddTodo, map[string]interface{}{"title": "Context switch cleanup"}},
+		{"todo tag rename work job", RenameTag, nil},
+		{"todo tag along with sam", AddTodo, map[string]interface{}{"title": "Tag along with sam"}},
+		{"todo fsck --repair", CheckStore, map[string]interface{}{"repair": true}},
+		{"todo fsck the old bike", AddTodo, map[string]interface{}{"title": "Fsck the old bike"}},
+		{"todo migrate --from bolt:a.db --to sqlite:b.db", MigrateStore, map[string]interface{}{
+			"from": "bolt:a.db", "to": "sqlite:b.db"}},
+		{"todo migrate the old notes", AddTodo, map[string]interface{}{"title": "Migrate the old notes"}},
+		{"todo ! bare shelves", AddTodo, map[string]interface{}{"title": "! bare shelves"}},
+	}
+
+	for _, test := range tests {
+		opts, err := getOpts(strings.Fields(test.args))
+		if err != nil {
+			t.Errorf("getOpts(%q): %v", test.args, err)
+			continue
+		}
+		if opts.option != test.option {
+			t.Errorf("getOpts(%q).option = %v, want %v", test.args, opts.option, test.option)
+		}
+		for key, want := range test.params {
+			if got := opts.params[key]; !reflect.DeepEqual(got, want) {
+				t.Errorf("getOpts(%q).params[%q] = %v, want %v", test.args, key, got, want)
+			}
+		}
+	}
+}
+
+func TestGetOptsErrors(t *testing.T) {
+	tests := []string{
+		"todo 1 bogus",
+		"todo 1 !",
+		"todo 1.2 bogus",
+		"todo 1 tomorrow bogus",
+		"todo buy milk -p bogus",
+		"todo buy milk -p -e",
+		"todo context @a,@b",
+		"todo project 1st",
+		"todo project kickoff status bogus",
+		"todo project kickoff delete now",
+		"todo migrate --from bolt:a.db",
+		"todo 2026-10-20..2026-10-01",
+	}
+
+	for _, args := range tests {
+		if _, err := getOpts(strings.Fields(args)); err == nil {
+			t.Errorf("getOpts(%q): expected an error", args)
+		}
+	}
+}
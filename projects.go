@@ -0,0 +1,274 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Project struct groups todos under a name. Todos refer to their
+// project by name
+type Project struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Status      string    `json:"status"`
+	Deadline    time.Time `json:"deadline,omitempty"`
+}
+
+const (
+	// ProjectActive status of a project being worked on
+	ProjectActive = "active"
+	// ProjectOnHold status of a project put aside for now
+	ProjectOnHold = "hold"
+	// ProjectDone status of a finished project
+	ProjectDone = "done"
+)
+
+var projectNameRe = regexp.MustCompile(`^[a-zA-Z][\w-]*$`)
+
+func newProject(name string) Project {
+	return Project{Name: name, Status: ProjectActive}
+}
+
+func (p Project) data() []byte {
+	data, _ := json.Marshal(p)
+	return data
+}
+
+func makeProject(data []byte) (Project, error) {
+	var p Project
+	err := json.Unmarshal(data, &p)
+	return p, err
+}
+
+func (p Project) deadlinestr() string {
+	if p.Deadline.IsZero() {
+		return "none"
+	}
+	return p.Deadline.Format("2 Jan 2006")
+}
+
+// projectProgress returns how many of the todos are done and the hours
+// still estimated for the pending ones, less what was already spent
+func projectProgress(todos []Todo, spent map[string]float32) (int, float32) {
+	done, remaining := 0, float32(0.0)
+	for _, todo := range todos {
+		if todo.Done {
+			done++
+			continue
+		}
+		if todo.Estimate > spent[todo.ID] {
+			remaining += todo.Estimate - spent[todo.ID]
+		}
+	}
+	return done, remaining
+}
+
+func percent(done int, total int) int {
+	if total == 0 {
+		return 0
+	}
+	return done * 100 / total
+}
+
+// ensureProject creates the project a todo is being put in if there is
+// none by that name yet
+func ensureProject(name string, repo Repository) error {
+	if name == "" {
+		return nil
+	}
+
+	_, err := repo.GetProject(UserKey, name)
+	if err == nil {
+		return nil
+	}
+
+	err = repo.SaveProject(UserKey, newProject(name))
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Created project %s\n", name)
+	return nil
+}
+
+func listProjects(opts Opts, repo Repository) error {
+	projects, err := repo.GetProjects(UserKey)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\nProjects\n--------\n")
+	for _, project := range projects {
+		todos, err := repo.GetTodosByProject(UserKey, project.Name)
+		if err != nil {
+			return err
+		}
+
+		spent, err := spentByTodo(todos, repo)
+		if err != nil {
+			return err
+		}
+
+		done, remaining := projectProgress(todos, spent)
+		fmt.Printf("%-16s %-6s %3d%% of %d done, %.1fh remaining, deadline %s\n",
+			project.Name, project.Status, percent(done, len(todos)), len(todos), remaining, project.deadlinestr())
+	}
+	fmt.Println()
+
+	return nil
+}
+
+func showProject(opts Opts, repo Repository) error {
+	project, err := repo.GetProject(UserKey, opts.params["project"].(string))
+	if err != nil {
+		return err
+	}
+
+	todos, err := repo.GetTodosByProject(UserKey, project.Name)
+	if err != nil {
+		return err
+	}
+	sortByPriority(todos)
+
+	fmt.Printf(`
+Project  : %s
+Status   : %s
+Deadline : %s
+`, project.Name, project.Status, project.deadlinestr())
+	if project.Description != "" {
+		fmt.Printf("About    : %s\n", project.Description)
+	}
+
+	spent, err := spentByTodo(todos, repo)
+	if err != nil {
+		return err
+	}
+
+	listMapping := printTodos(opts, todos, map[string]bool{}, spent)
+	repo.SetListMapping(listMapping)
+
+	done, remaining := projectProgress(todos, spent)
+	fmt.Printf("%d%% complete, %.1f hours remaining\n\n", percent(done, len(todos)), remaining)
+
+	return nil
+}
+
+// updateProject sets the description, status or deadline of a project,
+// creating it if needed, or deletes a project none of the todos are in
+func updateProject(opts Opts, repo Repository) error {
+	name := opts.params["project"].(string)
+
+	project, err := repo.GetProject(UserKey, name)
+	if err != nil {
+		project = newProject(name)
+	}
+
+	if _, present := opts.params["delete"]; present {
+		todos, err := repo.GetTodosByProject(UserKey, name)
+		if err != nil {
+			return err
+		}
+		if len(todos) > 0 {
+			return fmt.Errorf("Project %s still has %d todos", name, len(todos))
+		}
+		return repo.DeleteProject(UserKey, name)
+	}
+
+	if descriptioni, present := opts.params["description"]; present {
+		project.Description = descriptioni.(string)
+	}
+
+	if statusi, present := opts.params["status"]; present {
+		project.Status = statusi.(string)
+	}
+
+	if deadlinei, present := opts.params["deadline"]; present {
+		project.Deadline = deadlinei.(time.Time)
+	}
+
+	return repo.SaveProject(UserKey, project)
+}
+
+// getProjectOpts reads todo project <name> [description <text> | status
+// <status> | deadline <date> | delete]
+func getProjectOpts(args []string) (Opts, error) {
+	var opts Opts
+	opts.params = map[string]interface{}{}
+
+	if len(args) == 0 {
+		return Opts{}, fmt.Errorf("Usage: todo project <name> [description <text> | status <status> | deadline <date> | delete]")
+	}
+
+	name := args[0]
+	if !projectNameRe.MatchString(name) {
+		return Opts{}, fmt.Errorf("Invalid project name %s, expected a word starting with a letter", name)
+	}
+	opts.params["project"] = name
+
+	if len(args) == 1 {
+		opts.option = ShowProject
+		opts.params["type"] = "project"
+		return opts, nil
+	}
+
+	opts.option = UpdateProject
+	value := strings.Join(args[2:], " ")
+	switch args[1] {
+	case "description":
+		opts.params["description"] = value
+	case "status":
+		switch value {
+		case ProjectActive, ProjectOnHold, ProjectDone:
+			opts.params["status"] = value
+		default:
+			return Opts{}, fmt.Errorf("Unknown project status %s, expected active, hold or done", value)
+		}
+	case "deadline":
+		if value == "none" {
+			opts.params["deadline"] = time.Time{}
+			break
+		}
+
+		deadline, err := toDate(value)
+		if err != nil {
+			return Opts{}, err
+		}
+		opts.params["deadline"] = deadline
+	case "delete":
+		if len(args) > 2 {
+			return Opts{}, fmt.Errorf("Unexpected arguments after delete: %s", value)
+		}
+		opts.params["delete"] = true
+	default:
+		return Opts{}, fmt.Errorf("Unknown project action %s, expected description, status, deadline or delete", args[1])
+	}
+
+	return opts, nil
+}
+
+// isProjectAction tells if name and action start a todo project command
+func isProjectAction(name string, action string) bool {
+	switch action {
+	case "description", "status", "deadline", "delete":
+		return projectNameRe.MatchString(name)
+	}
+	return false
+}
+
+// isProject reads +name as putting the todo in the project, +none takes
+// it out of its project
+func isProject(param string) (bool, string) {
+	if !strings.HasPrefix(param, "+") || !projectNameRe.MatchString(param[1:]) {
+		return false, ""
+	}
+
+	name := param[1:]
+	if name == "none" {
+		name = ""
+	}
+
+	return true, name
+}
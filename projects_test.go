@@ -0,0 +1,108 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetProjectOpts(t *testing.T) {
+	deadline := today().AddDate(0, 0, 1)
+	tests := []struct {
+		args   []string
+		option OpType
+		key    string
+		want   interface{}
+	}{
+		{[]string{"kickoff"}, ShowProject, "project", "kickoff"},
+		{[]string{"kickoff", "description", "plan", "the", "launch"}, UpdateProject, "description", "plan the launch"},
+		{[]string{"kickoff", "status", "hold"}, UpdateProject, "status", ProjectOnHold},
+		{[]string{"kickoff", "deadline", "tomorrow"}, UpdateProject, "deadline", deadline},
+		{[]string{"kickoff", "deadline", "none"}, UpdateProject, "deadline", time.Time{}},
+		{[]string{"kickoff", "delete"}, UpdateProject, "delete", true},
+	}
+
+	for _, test := range tests {
+		opts, err := getProjectOpts(test.args)
+		if err != nil {
+			t.Errorf("getProjectOpts(%v): %v", test.args, err)
+			continue
+		}
+		if opts.option != test.option || opts.params[test.key] != test.want {
+			t.Errorf("getProjectOpts(%v) = %v %v, want %v with %s %v", test.args,
+				opts.option, opts.params, test.option, test.key, test.want)
+		}
+	}
+
+	invalid := [][]string{
+		{},
+		{"2nd"},
+		{"kickoff", "status", "stalled"},
+		{"kickoff", "deadline", "someday"},
+		{"kickoff", "delete", "now"},
+		{"kickoff", "rename"},
+	}
+	for _, args := range invalid {
+		if _, err := getProjectOpts(args); err == nil {
+			t.Errorf("getProjectOpts(%v), want an error", args)
+		}
+	}
+}
+
+func TestIsProjectAction(t *testing.T) {
+	tests := []struct {
+		name, action string
+		want         bool
+	}{
+		{"kickoff", "status", true},
+		{"kickoff", "delete", true},
+		{"kickoff", "prep", false},
+		{"2nd", "status", false},
+	}
+
+	for _, test := range tests {
+		if got := isProjectAction(test.name, test.action); got != test.want {
+			t.Errorf("isProjectAction(%q, %q) = %v, want %v", test.name, test.action, got, test.want)
+		}
+	}
+}
+
+func TestProjectProgress(t *testing.T) {
+	done := newTestTodo("done", today())
+	done.Done = true
+	done.Estimate = 4
+	started := newTestTodo("started", today())
+	started.Estimate = 3
+	overrun := newTestTodo("overrun", today())
+	overrun.Estimate = 1
+	fresh := newTestTodo("fresh", today())
+	fresh.Estimate = 2
+
+	spent := map[string]float32{started.ID: 1, overrun.ID: 2, done.ID: 5}
+	count, remaining := projectProgress([]Todo{done, started, overrun, fresh}, spent)
+	if count != 1 || remaining != 4 {
+		t.Errorf("projectProgress = %d done, %v remaining, want 1 done, 4 remaining", count, remaining)
+	}
+}
+
+func TestDeleteProjectWithTodos(t *testing.T) {
+	repo := newMemoryRepo()
+	todo := newTestTodo("prep", today())
+	todo.Project = "kickoff"
+	createTodos(t, repo, todo)
+	err := repo.SaveProject(UserKey, newProject("kickoff"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = runCommands(t, repo, todo, "todo project kickoff delete"); err == nil {
+		t.Errorf("deleting a project with todos, want an error")
+	}
+
+	err = runCommands(t, repo, todo, "todo 1 +none", "todo project kickoff delete")
+	if err != nil {
+		t.Fatalf("deleting an empty project: %v", err)
+	}
+	if _, err = repo.GetProject(UserKey, "kickoff"); err == nil {
+		t.Errorf("project kickoff still there after delete")
+	}
+}
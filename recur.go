@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/xid"
+)
+
+// recurrence is a parsed recurrence rule. The rules are
+//
+//	daily            every day
+//	weekdays         monday to friday
+//	weekly[:mon,thu] on the given days, the due weekday if none given
+//	monthly[:15]     on the given day of the month, the due day if none
+//	every:3d         3 days after completion, w and m units work too
+type recurrence struct {
+	kind  string
+	days  []time.Weekday
+	day   int
+	every int
+	unit  string
+}
+
+var everyRe = regexp.MustCompile(`^every:(\d+)([dwm])$`)
+
+func parseRecurrence(rule string) (recurrence, error) {
+	rule = strings.ToLower(rule)
+	parts := strings.SplitN(rule, ":", 2)
+
+	switch {
+	case rule == "daily" || rule == "weekdays":
+		return recurrence{kind: rule}, nil
+	case parts[0] == "weekly":
+		r := recurrence{kind: "weekly"}
+		if len(parts) == 2 {
+			for _, name := range strings.Split(parts[1], ",") {
+				weekday, ok := weekdays[name]
+				if !ok {
+					return recurrence{}, fmt.Errorf("Unknown weekday %s in recurrence %s", name, rule)
+				}
+				r.days = append(r.days, weekday)
+			}
+		}
+		return r, nil
+	case parts[0] == "monthly":
+		r := recurrence{kind: "monthly"}
+		if len(parts) == 2 {
+			day, err := strconv.Atoi(parts[1])
+			if err != nil || day < 1 || day > 31 {
+				return recurrence{}, fmt.Errorf("Invalid day of month in recurrence %s", rule)
+			}
+			r.day = day
+		}
+		return r, nil
+	}
+
+	if match := everyRe.FindStringSubmatch(rule); match != nil {
+		every, _ := strconv.Atoi(match[1])
+		if every > 0 {
+			return recurrence{kind: "every", every: every, unit: match[2]}, nil
+		}
+	}
+
+	return recurrence{}, fmt.Errorf("Unknown recurrence %s, expected daily, weekdays, weekly:mon,thu, monthly:15 or every:3d", rule)
+}
+
+// next returns the due date of the occurrence following one due on due
+// and completed on completed. Scheduled rules skip occurrences that are
+// already in the past
+func (r recurrence) next(due time.Time, completed time.Time) time.Time {
+	if r.kind == "every" {
+		return addPeriod(startOfDay(completed), r.every, r.unit)
+	}
+
+	next := r.step(due, due)
+	for next.Before(startOfDay(completed)) {
+		next = r.step(next, due)
+	}
+	return next
+}
+
+// step returns the first date matching the rule after from. first is the
+// due date of the todo the series continues from
+func (r recurrence) step(from time.Time, first time.Time) time.Time {
+	switch r.kind {
+	case "weekdays":
+		next := from.AddDate(0, 0, 1)
+		for next.Weekday() == time.Saturday || next.Weekday() == time.Sunday {
+			next = next.AddDate(0, 0, 1)
+		}
+		return next
+	case "weekly":
+		if len(r.days) == 0 {
+			return from.AddDate(0, 0, 7)
+		}
+		next := from.AddDate(0, 0, 1)
+		for !r.onDay(next.Weekday()) {
+			next = next.AddDate(0, 0, 1)
+		}
+		return next
+	case "monthly":
+		day := r.day
+		if day == 0 {
+			day = first.Day()
+		}
+
+		// Months too short for the day get their last day instead
+		year, month, _ := from.Date()
+		for {
+			month++
+			last := time.Date(year, month+1, 0, 0, 0, 0, 0, from.Location()).Day()
+			next := time.Date(year, month, min(day, last), 0, 0, 0, 0, from.Location())
+			if next.After(from) {
+				return next
+			}
+		}
+	default:
+		return from.AddDate(0, 0, 1)
+	}
+}
+
+func (r recurrence) onDay(weekday time.Weekday) bool {
+	for _, day := range r.days {
+		if day == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+// markDone marks the todo done or pending like setDone. When a recurring
+// todo gets done the next occurrence of its series is returned, for the
+// caller to create along with the update. The todo remembers the ID of
+// its next occurrence, so marking it pending and done again does not
+// create another one
+func (t *Todo) markDone(done bool) (*Todo, error) {
+	wasDone := t.Done
+	err := t.setDone(done)
+	if err != nil || wasDone || !done || t.Recur == "" || t.NextID != "" {
+		return nil, err
+	}
+
+	rule, err := parseRecurrence(t.Recur)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.SeriesID == "" {
+		t.SeriesID = t.ID
+	}
+
+	next := *t
+	next.ID = xid.New().String()
+	t.NextID = next.ID
+	next.Done = false
+	due := t.dueLocal()
+	next.Due = withClock(rule.next(startOfDay(due), time.Now()), due)
+	next.Created = time.Time{}
+	next.Modified = time.Time{}
+	next.Completed = time.Time{}
+	next.Postponed = 0
+	next.Notes = nil
+	next.Subtasks = nil
+	for _, subtask := range t.Subtasks {
+		next.Subtasks = append(next.Subtasks, Subtask{Title: subtask.Title})
+	}
+	next.Tags = append([]string{}, t.Tags...)
+	next.Contexts = append([]string(nil), t.Contexts...)
+	next.DependsOn = append([]string{}, t.DependsOn...)
+
+	return &next, nil
+}
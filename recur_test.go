@@ -0,0 +1,102 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseRecurrence(t *testing.T) {
+	tests := []struct {
+		rule string
+		want recurrence
+	}{
+		{"daily", recurrence{kind: "daily"}},
+		{"Weekdays", recurrence{kind: "weekdays"}},
+		{"weekly", recurrence{kind: "weekly"}},
+		{"weekly:mon,thu", recurrence{kind: "weekly", days: []time.Weekday{time.Monday, time.Thursday}}},
+		{"monthly", recurrence{kind: "monthly"}},
+		{"monthly:15", recurrence{kind: "monthly", day: 15}},
+		{"every:3d", recurrence{kind: "every", every: 3, unit: "d"}},
+		{"every:2w", recurrence{kind: "every", every: 2, unit: "w"}},
+	}
+
+	for _, test := range tests {
+		got, err := parseRecurrence(test.rule)
+		if err != nil {
+			t.Errorf("parseRecurrence(%q) returned error: %v", test.rule, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("parseRecurrence(%q) = %+v, want %+v", test.rule, got, test.want)
+		}
+	}
+}
+
+func TestParseRecurrenceInvalid(t *testing.T) {
+	for _, rule := range []string{"", "hourly", "weekly:someday", "monthly:0", "monthly:32", "every:0d", "every:3y"} {
+		if got, err := parseRecurrence(rule); err == nil {
+			t.Errorf("parseRecurrence(%q) = %+v, want an error", rule, got)
+		}
+	}
+}
+
+func TestRecurrenceNext(t *testing.T) {
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.Local)
+	}
+
+	tests := []struct {
+		rule      string
+		due       time.Time
+		completed time.Time
+		want      time.Time
+	}{
+		{"daily", date(2026, 10, 5), date(2026, 10, 5), date(2026, 10, 6)},
+		{"daily", date(2026, 10, 1), date(2026, 10, 5), date(2026, 10, 5)},
+		{"weekdays", date(2026, 10, 9), date(2026, 10, 9), date(2026, 10, 12)},
+		{"weekly", date(2026, 10, 5), date(2026, 10, 5), date(2026, 10, 12)},
+		{"weekly:mon,thu", date(2026, 10, 5), date(2026, 10, 5), date(2026, 10, 8)},
+		{"weekly:mon,thu", date(2026, 10, 8), date(2026, 10, 8), date(2026, 10, 12)},
+		{"monthly", date(2026, 1, 31), date(2026, 1, 31), date(2026, 2, 28)},
+		{"monthly:15", date(2026, 10, 15), date(2026, 10, 20), date(2026, 11, 15)},
+		{"every:3d", date(2026, 10, 1), date(2026, 10, 10).Add(17 * time.Hour), date(2026, 10, 13)},
+		{"every:1w", date(2026, 10, 1), date(2026, 10, 2), date(2026, 10, 9)},
+	}
+
+	for _, test := range tests {
+		rule, err := parseRecurrence(test.rule)
+		if err != nil {
+			t.Fatalf("parseRecurrence(%q): %v", test.rule, err)
+		}
+
+		if got := rule.next(test.due, test.completed); !got.Equal(test.want) {
+			t.Errorf("%s next after %s completed %s = %s, want %s", test.rule,
+				test.due.Format("2006-01-02"), test.completed.Format("2006-01-02"),
+				got.Format("2006-01-02"), test.want.Format("2006-01-02"))
+		}
+	}
+}
+
+func TestMarkDoneSpawnsOnce(t *testing.T) {
+	todo := newTestTodo("Water plants", today())
+	todo.Recur = "daily"
+
+	next, err := todo.markDone(true)
+	if err != nil || next == nil {
+		t.Fatalf("markDone(true) = %v, %v, want the next occurrence", next, err)
+	}
+	if todo.NextID != next.ID || next.SeriesID != todo.ID {
+		t.Errorf("next occurrence %s of series %s not linked to %s", next.ID, next.SeriesID, todo.ID)
+	}
+
+	_, err = todo.markDone(false)
+	if err != nil {
+		t.Fatalf("markDone(false): %v", err)
+	}
+
+	again, err := todo.markDone(true)
+	if err != nil || again != nil {
+		t.Errorf("markDone(true) again = %v, %v, want no new occurrence", again, err)
+	}
+}
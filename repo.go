@@ -1,13 +1,14 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
 	"time"
 
 	bolt "go.etcd.io/bbolt"
 )
 
-// TodoRepo struct
+// TodoRepo struct is the Repository backed by a bbolt file
 type TodoRepo struct {
 	db *bolt.DB
 }
@@ -17,7 +18,12 @@ func (r *TodoRepo) Init(db *bolt.DB) {
 	r.db = db
 }
 
-// Create method
+// Close method
+func (r *TodoRepo) Close() error {
+	return r.db.Close()
+}
+
+// CreateTodo method
 func (r *TodoRepo) CreateTodo(userID string, t Todo) error {
 	err := r.db.Update(func(tx *bolt.Tx) error {
 		userBucket, err := tx.CreateBucketIfNotExists([]byte(userID))
@@ -25,36 +31,7 @@ func (r *TodoRepo) CreateTodo(userID string, t Todo) error {
 			return err
 		}
 
-		// Add todo to user bucket
-		err = userBucket.Put(t.id(), t.data())
-		if err != nil {
-			return err
-		}
-
-		// Add to date bucket for easy search on dates
-		dateBucket, err := userBucket.CreateBucketIfNotExists(t.due())
-		if err != nil {
-			return err
-		}
-		err = dateBucket.Put(t.id(), t.id())
-		if err != nil {
-			return err
-		}
-
-		// Add to pending bucket
-		if !t.Done {
-			pendingBucket, err := userBucket.CreateBucketIfNotExists(PendingKey)
-			if err != nil {
-				return err
-			}
-
-			err = pendingBucket.Put(t.id(), t.id())
-			if err != nil {
-				return err
-			}
-		}
-
-		return nil
+		return writeTodo(userBucket, nil, t)
 	})
 
 	return err
@@ -128,9 +105,12 @@ func (r *TodoRepo) GetTodosByDate(userID string, date time.Time) ([]Todo, error)
 	return todos, err
 }
 
-// GetTodo method
-func (r *TodoRepo) GetTodo(userID string, id string) (Todo, error) {
-	var todo Todo
+// GetTodosInRange method returns the todos due between from and to,
+// both days included, ordered by due date
+func (r *TodoRepo) GetTodosInRange(userID string, from time.Time, to time.Time) ([]Todo, error) {
+	todos := []Todo{}
+	fromKey := []byte(from.Format("2006-01-02"))
+	toKey := []byte(to.Format("2006-01-02"))
 
 	err := r.db.View(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(userID))
@@ -138,171 +118,420 @@ func (r *TodoRepo) GetTodo(userID string, id string) (Todo, error) {
 			return nil
 		}
 
-		data := bucket.Get([]byte(id))
-		if data == nil {
-			return fmt.Errorf("Could find tod with ID: %s", id)
-		}
+		// Date buckets sort by their key, so seek to the first day and walk
+		// forward. Todos and other buckets are interleaved with the date
+		// buckets and are skipped
+		c := bucket.Cursor()
+		for k, v := c.Seek(fromKey); k != nil && bytes.Compare(k, toKey) <= 0; k, v = c.Next() {
+			if v != nil || !isDateKey(k) {
+				continue
+			}
 
-		var err error
-		todo, err = makeTodo(data)
-		if err != nil {
-			return err
+			dc := bucket.Bucket(k).Cursor()
+			for id, _ := dc.First(); id != nil; id, _ = dc.Next() {
+				data := bucket.Get(id)
+				if data != nil {
+					todo, err := makeTodo(data)
+					if err != nil {
+						return err
+					}
+
+					todos = append(todos, todo)
+				}
+			}
 		}
 
 		return nil
 	})
 
-	return todo, err
+	return todos, err
 }
 
-// SetTodoDone method
-func (r *TodoRepo) SetTodoDone(userID string, todoID string, status bool) error {
-	err := r.db.Update(func(tx *bolt.Tx) error {
-		userBucket := tx.Bucket([]byte(userID))
-		if userBucket == nil {
-			return fmt.Errorf("Unable to find user bucket for %s", userID)
-		}
+// GetAllTodos method returns every todo of the user, done or not,
+// ordered by ID
+func (r *TodoRepo) GetAllTodos(userID string) ([]Todo, error) {
+	todos := []Todo{}
 
-		data := userBucket.Get([]byte(todoID))
-		if data == nil {
-			return fmt.Errorf("No todo found for ID: %s", todoID)
-		}
+	err := r.ForEachTodo(userID, func(todo Todo) error {
+		todos = append(todos, todo)
+		return nil
+	})
 
-		todo, err := makeTodo(data)
-		if err != nil {
-			return err
-		}
+	return todos, err
+}
 
-		todo.Done = status
-		todo.Effort = 1.0
-		err = userBucket.Put([]byte(todo.ID), todo.data())
-		if err != nil {
-			return err
+// ForEachTodo method calls fn with every todo of the user in ID order,
+// one at a time, stopping at the first error. fn runs inside the read
+// transaction, so it may read from the store but must not write to it
+func (r *TodoRepo) ForEachTodo(userID string, fn func(Todo) error) error {
+	return r.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(userID))
+		if bucket == nil {
+			return nil
 		}
 
-		if !todo.Done {
-			pendingBucket, err := userBucket.CreateBucketIfNotExists(PendingKey)
+		// Nested buckets have a nil value, everything else is a todo
+		c := bucket.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			if v == nil {
+				continue
+			}
+
+			todo, err := makeTodo(v)
 			if err != nil {
 				return err
 			}
 
-			err = pendingBucket.Put(todo.id(), nil)
+			err = fn(todo)
 			if err != nil {
 				return err
 			}
 		}
 
-		if todo.Done {
-			pendingBucket := userBucket.Bucket(PendingKey)
-			if pendingBucket != nil && pendingBucket.Get(todo.id()) != nil {
-				pendingBucket.Delete(todo.id())
+		return nil
+	})
+}
+
+// GetTodosCompletedInRange method returns the todos completed between
+// from and to, both days included. There is no index on completion, the
+// todos of the user are scanned
+func (r *TodoRepo) GetTodosCompletedInRange(userID string, from time.Time, to time.Time) ([]Todo, error) {
+	todos, err := r.GetAllTodos(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return completedInRange(todos, from, to), nil
+}
+
+// GetTodosByTag method returns the todos tagged with tag, ordered by ID,
+// from the tag index
+func (r *TodoRepo) GetTodosByTag(userID string, tag string) ([]Todo, error) {
+	todos := []Todo{}
+
+	err := r.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(userID))
+		if bucket == nil {
+			return nil
+		}
+
+		tagsBucket := bucket.Bucket(TagsKey)
+		if tagsBucket == nil || tag == "" {
+			return nil
+		}
+
+		tagBucket := tagsBucket.Bucket([]byte(tag))
+		if tagBucket == nil {
+			return nil
+		}
+
+		c := tagBucket.Cursor()
+		for id, _ := c.First(); id != nil; id, _ = c.Next() {
+			data := bucket.Get(id)
+			if data != nil {
+				todo, err := makeTodo(data)
+				if err != nil {
+					return err
+				}
+
+				todos = append(todos, todo)
 			}
 		}
 
 		return nil
 	})
 
-	return err
+	return todos, err
 }
 
-// SetTodoEffort method
-func (r *TodoRepo) SetTodoEffort(userID string, todoID string, effort float32) error {
-	err := r.db.Update(func(tx *bolt.Tx) error {
-		userBucket := tx.Bucket([]byte(userID))
-		if userBucket == nil {
-			return fmt.Errorf("Unable to find user bucket for %s", userID)
+// GetTags method returns the tags in use, in order
+func (r *TodoRepo) GetTags(userID string) ([]string, error) {
+	tags := []string{}
+
+	err := r.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(userID))
+		if bucket == nil {
+			return nil
 		}
 
-		data := userBucket.Get([]byte(todoID))
-		if data == nil {
-			return fmt.Errorf("No todo found for ID: %s", todoID)
+		tagsBucket := bucket.Bucket(TagsKey)
+		if tagsBucket == nil {
+			return nil
 		}
 
-		todo, err := makeTodo(data)
-		if err != nil {
-			return err
+		c := tagsBucket.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			if v != nil {
+				continue
+			}
+
+			// Databases written before empty buckets were removed can
+			// still have buckets of tags no todo has any more
+			if first, _ := tagsBucket.Bucket(k).Cursor().First(); first != nil {
+				tags = append(tags, string(k))
+			}
 		}
 
-		// Update Todo in user bucket
-		todo.Effort = effort
-		err = userBucket.Put([]byte(todo.ID), todo.data())
-		return err
+		return nil
 	})
 
-	return err
+	return tags, err
 }
 
-// SetTodoDue method
-func (r *TodoRepo) SetTodoDue(userID string, todoID string, due time.Time) error {
-	err := r.db.Update(func(tx *bolt.Tx) error {
-		userBucket := tx.Bucket([]byte(userID))
-		if userBucket == nil {
-			return fmt.Errorf("Unable to find user bucket for %s", userID)
+// GetTodosByWord method returns the todos with the word in their title
+// or notes from the search index, or with prefix set the todos with a
+// word starting with it. Words are lower case
+func (r *TodoRepo) GetTodosByWord(userID string, word string, prefix bool) ([]Todo, error) {
+	todos := []Todo{}
+
+	err := r.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(userID))
+		if bucket == nil {
+			return nil
 		}
 
-		data := userBucket.Get([]byte(todoID))
-		if data == nil {
-			return fmt.Errorf("No todo found for ID: %s", todoID)
+		searchBucket := bucket.Bucket(SearchKey)
+		if searchBucket == nil || word == "" {
+			return nil
 		}
 
-		todo, err := makeTodo(data)
-		if err != nil {
-			return err
+		seen := map[string]bool{}
+		c := searchBucket.Cursor()
+		for k, v := c.Seek([]byte(word)); k != nil; k, v = c.Next() {
+			if !bytes.HasPrefix(k, []byte(word)) || (!prefix && len(k) != len(word)) {
+				break
+			}
+			if v != nil {
+				continue
+			}
+
+			wc := searchBucket.Bucket(k).Cursor()
+			for id, _ := wc.First(); id != nil; id, _ = wc.Next() {
+				data := bucket.Get(id)
+				if data == nil || seen[string(id)] {
+					continue
+				}
+				seen[string(id)] = true
+
+				todo, err := makeTodo(data)
+				if err != nil {
+					return err
+				}
+
+				todos = append(todos, todo)
+			}
+		}
+
+		return nil
+	})
+
+	return todos, err
+}
+
+// GetUsers method returns the IDs of all users having a bucket
+func (r *TodoRepo) GetUsers() ([]string, error) {
+	users := []string{}
+
+	err := r.db.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+			if isUserBucket(name) {
+				users = append(users, string(name))
+			}
+			return nil
+		})
+	})
+
+	return users, err
+}
+
+// GetTodo method
+func (r *TodoRepo) GetTodo(userID string, id string) (Todo, error) {
+	var todo Todo
+
+	err := r.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(userID))
+		if bucket == nil {
+			return nil
 		}
 
-		// Remove from the curret date bucket
-		currentDateBucket := userBucket.Bucket(todo.due())
-		if currentDateBucket != nil {
-			currentDateBucket.Delete(todo.id())
+		data := bucket.Get([]byte(id))
+		if data == nil {
+			return fmt.Errorf("Could find tod with ID: %s", id)
 		}
 
-		// Update Todo in user bucket
-		todo.Due = due
-		err = userBucket.Put([]byte(todo.ID), todo.data())
+		var err error
+		todo, err = makeTodo(data)
 		if err != nil {
 			return err
 		}
 
-		// Add to new date bucket
-		newDateBucket, err := userBucket.CreateBucketIfNotExists(todo.due())
-		if err != nil {
-			return err
+		return nil
+	})
+
+	return todo, err
+}
+
+// MutateTodo method loads the todo, lets change modify it and writes
+// it back. The record and all its index entries are updated in a
+// single transaction, nothing is written if change returns an error
+func (r *TodoRepo) MutateTodo(userID string, todoID string, change func(*Todo) error) error {
+	return r.MutateAndCreateTodo(userID, todoID, func(todo *Todo) (*Todo, error) {
+		return nil, change(todo)
+	})
+}
+
+// MutateAndCreateTodo method is MutateTodo where change can also
+// return a new todo to be created in the same transaction
+func (r *TodoRepo) MutateAndCreateTodo(userID string, todoID string, change func(*Todo) (*Todo, error)) error {
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		userBucket := tx.Bucket([]byte(userID))
+		if userBucket == nil {
+			return fmt.Errorf("Unable to find user bucket for %s", userID)
 		}
-		err = newDateBucket.Put(todo.id(), todo.id())
 
-		return err
+		return mutateStoredTodo(userBucket, todoID, change)
 	})
 
 	return err
 }
 
-// SetTodoTags method
-func (r *TodoRepo) SetTodoTags(userID string, todoID string, tags []string) error {
-	err := r.db.Update(func(tx *bolt.Tx) error {
+// MutateTodos method applies change to each of the todos in a single
+// transaction, none of them is written if change fails for any. A todo
+// listed more than once is changed once
+func (r *TodoRepo) MutateTodos(userID string, todoIDs []string, change func(*Todo) error) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
 		userBucket := tx.Bucket([]byte(userID))
 		if userBucket == nil {
 			return fmt.Errorf("Unable to find user bucket for %s", userID)
 		}
 
-		data := userBucket.Get([]byte(todoID))
-		if data == nil {
-			return fmt.Errorf("No todo found for ID: %s", todoID)
+		return mutateStoredTodos(userBucket, uniqueIDs(todoIDs), change)
+	})
+}
+
+// ReplaceTag method puts to in place of from on every todo tagged from
+// and returns how many were changed. The tags are looked up, checked and
+// rewritten in a single transaction
+func (r *TodoRepo) ReplaceTag(userID string, from string, to string, merge bool) (int, error) {
+	count := 0
+
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		userBucket := tx.Bucket([]byte(userID))
+		if userBucket == nil {
+			return checkReplaceTag(from, to, merge, 0, 0)
 		}
 
-		todo, err := makeTodo(data)
+		tagged := taggedIDs(userBucket, from)
+		err := checkReplaceTag(from, to, merge, len(tagged), len(taggedIDs(userBucket, to)))
 		if err != nil {
 			return err
 		}
 
-		todo.Tags = tags
-		err = userBucket.Put(todo.id(), todo.data())
+		count = len(tagged)
+		return mutateStoredTodos(userBucket, tagged, func(todo *Todo) error {
+			todo.replaceTag(from, to)
+			return nil
+		})
+	})
+
+	return count, err
+}
+
+// taggedIDs returns the IDs in the tag index under tag
+func taggedIDs(userBucket *bolt.Bucket, tag string) []string {
+	ids := []string{}
+
+	tagsBucket := userBucket.Bucket(TagsKey)
+	if tagsBucket == nil {
+		return ids
+	}
+
+	tagBucket := tagsBucket.Bucket([]byte(tag))
+	if tagBucket == nil {
+		return ids
+	}
+
+	c := tagBucket.Cursor()
+	for id, _ := c.First(); id != nil; id, _ = c.Next() {
+		ids = append(ids, string(id))
+	}
+
+	return ids
+}
+
+func mutateStoredTodos(userBucket *bolt.Bucket, todoIDs []string, change func(*Todo) error) error {
+	for _, todoID := range todoIDs {
+		err := mutateStoredTodo(userBucket, todoID, func(todo *Todo) (*Todo, error) {
+			return nil, change(todo)
+		})
 		if err != nil {
 			return err
 		}
+	}
+
+	return nil
+}
+
+func mutateStoredTodo(userBucket *bolt.Bucket, todoID string, change func(*Todo) (*Todo, error)) error {
+	data := userBucket.Get([]byte(todoID))
+	if data == nil {
+		return fmt.Errorf("No todo found for ID: %s", todoID)
+	}
+
+	// Decoded twice so that change can't alter the old copy through
+	// shared slices
+	old, err := makeTodo(data)
+	if err != nil {
+		return err
+	}
+	todo, err := makeTodo(data)
+	if err != nil {
+		return err
+	}
+
+	created, err := change(&todo)
+	if err != nil {
+		return err
+	}
+
+	err = writeTodo(userBucket, &old, todo)
+	if err != nil || created == nil {
 		return err
+	}
+
+	return writeTodo(userBucket, nil, *created)
+}
+
+// SetTodoDone method, completing a recurring todo also creates its next
+// occurrence
+func (r *TodoRepo) SetTodoDone(userID string, todoID string, status bool) error {
+	return r.MutateAndCreateTodo(userID, todoID, func(todo *Todo) (*Todo, error) {
+		return todo.markDone(status)
 	})
+}
 
-	return err
+// SetTodoEstimate method
+func (r *TodoRepo) SetTodoEstimate(userID string, todoID string, estimate float32) error {
+	return r.MutateTodo(userID, todoID, func(todo *Todo) error {
+		todo.Estimate = estimate
+		return nil
+	})
+}
+
+// SetTodoDue method
+func (r *TodoRepo) SetTodoDue(userID string, todoID string, due time.Time) error {
+	return r.MutateTodo(userID, todoID, func(todo *Todo) error {
+		todo.Due = due
+		return nil
+	})
+}
+
+// SetTodoTags method
+func (r *TodoRepo) SetTodoTags(userID string, todoID string, tags []string) error {
+	return r.MutateTodo(userID, todoID, func(todo *Todo) error {
+		todo.Tags = tags
+		return nil
+	})
 }
 
 // DeleteTodo method
@@ -313,7 +542,6 @@ func (r *TodoRepo) DeleteTodo(userID string, todoID string) error {
 			return fmt.Errorf("Unable to find user bucket for %s", userID)
 		}
 
-		// First retrieve the Todo
 		data := userBucket.Get([]byte(todoID))
 		if data == nil {
 			return fmt.Errorf("No todo found for ID: %s", todoID)
@@ -323,50 +551,98 @@ func (r *TodoRepo) DeleteTodo(userID string, todoID string) error {
 			return err
 		}
 
-		// Delete from the date bucket
-		dateBucket := userBucket.Bucket(todo.due())
-		if dateBucket == nil {
-			return fmt.Errorf("No date bucket found for date %v", todo.datestr())
-		}
-		err = dateBucket.Delete(todo.id())
-		if err != nil {
-			return err
-		}
-
-		// Delete from teh pending bucket, if present
-		pendingBucket := userBucket.Bucket(PendingKey)
-		if pendingBucket != nil {
-			err = pendingBucket.Delete([]byte(todoID))
+		for _, e := range indexEntries(todo) {
+			err = deleteIndexEntry(userBucket, e)
 			if err != nil {
 				return err
 			}
 		}
 
-		// Finally delete from user bucket
-		err = userBucket.Delete([]byte(todoID))
-		return err
+		err = deleteSessions(userBucket, todoID)
+		if err != nil {
+			return err
+		}
+
+		return userBucket.Delete([]byte(todoID))
 	})
 
 	return err
 }
 
-// UpdateTodo method
+// UpdateTodo method replaces the todo stored under todoID
 func (r *TodoRepo) UpdateTodo(userID string, todoID string, todo Todo) error {
-	err := r.DeleteTodo(userID, todoID)
+	return r.MutateTodo(userID, todoID, func(t *Todo) error {
+		*t = todo
+		return nil
+	})
+}
+
+// writeTodo stores the todo in the user bucket and brings its index
+// entries in line with it. old is the todo as it is currently stored,
+// nil for a new todo; entries it had that todo no longer needs are
+// removed, as is the old record if the ID changed
+func writeTodo(userBucket *bolt.Bucket, old *Todo, todo Todo) error {
+	prepareWrite(old, &todo, time.Now())
+
+	keep := map[string]bool{}
+	for _, e := range indexEntries(todo) {
+		keep[e.String()] = true
+	}
+
+	if old != nil {
+		for _, e := range indexEntries(*old) {
+			if keep[e.String()] {
+				continue
+			}
+
+			err := deleteIndexEntry(userBucket, e)
+			if err != nil {
+				return err
+			}
+		}
+
+		if old.ID != todo.ID {
+			err := userBucket.Delete(old.id())
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	err := userBucket.Put(todo.id(), todo.data())
 	if err != nil {
 		return err
 	}
 
-	return r.CreateTodo(userID, todo)
+	for _, e := range indexEntries(todo) {
+		err = putIndexEntry(userBucket, e)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func isUserBucket(name []byte) bool {
+	return !bytes.Equal(name, ListingKey) && !bytes.Equal(name, MetaKey)
 }
 
 // SetListMapping is a method to persist the todo list index that
 // is dieplayed to teh user to ID. This mapping is persisted after each
-// display of the Todos. Users will provide just the index like 1, 2, ..
-// for subsequent operations and the mapping will be retrieved to fetch the ID
+// display of the Todos, replacing the one of the previous display. Users
+// will provide just the index like 1, 2, .. for subsequent operations
+// and the mapping will be retrieved to fetch the ID
 func (r *TodoRepo) SetListMapping(mapping map[string]string) {
 	r.db.Update(func(t *bolt.Tx) error {
-		bucket, err := t.CreateBucketIfNotExists(ListingKey)
+		if t.Bucket(ListingKey) != nil {
+			err := t.DeleteBucket(ListingKey)
+			if err != nil {
+				return err
+			}
+		}
+
+		bucket, err := t.CreateBucket(ListingKey)
 		if err != nil {
 			return err
 		}
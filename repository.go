@@ -0,0 +1,155 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Repository is the store the operations work against. TodoRepo keeps
+// the todos in a bbolt file, SQLiteRepo in a SQLite database and
+// MemoryRepo keeps them in memory
+type Repository interface {
+	CreateTodo(userID string, t Todo) error
+	GetTodo(userID string, id string) (Todo, error)
+	GetPendingTodos(userID string) ([]Todo, error)
+	GetTodosByDate(userID string, date time.Time) ([]Todo, error)
+	GetTodosInRange(userID string, from time.Time, to time.Time) ([]Todo, error)
+	GetAllTodos(userID string) ([]Todo, error)
+	ForEachTodo(userID string, fn func(Todo) error) error
+	GetTodosCompletedInRange(userID string, from time.Time, to time.Time) ([]Todo, error)
+	GetTodosByTag(userID string, tag string) ([]Todo, error)
+	GetTags(userID string) ([]string, error)
+	GetTodosByWord(userID string, word string, prefix bool) ([]Todo, error)
+	GetUsers() ([]string, error)
+	SetTodoDone(userID string, todoID string, status bool) error
+	SetTodoDue(userID string, todoID string, due time.Time) error
+	SetTodoTags(userID string, todoID string, tags []string) error
+	SetTodoEstimate(userID string, todoID string, estimate float32) error
+	DeleteTodo(userID string, todoID string) error
+	UpdateTodo(userID string, todoID string, todo Todo) error
+	MutateTodo(userID string, todoID string, change func(*Todo) error) error
+	MutateAndCreateTodo(userID string, todoID string, change func(*Todo) (*Todo, error)) error
+	MutateTodos(userID string, todoIDs []string, change func(*Todo) error) error
+	ReplaceTag(userID string, from string, to string, merge bool) (int, error)
+	AddSession(userID string, session Session) error
+	StopSession(userID string, end time.Time) (Session, error)
+	GetRunningSession(userID string) (*Session, error)
+	GetSessions(userID string, todoID string) ([]Session, error)
+	SaveProject(userID string, project Project) error
+	GetProject(userID string, name string) (Project, error)
+	GetProjects(userID string) ([]Project, error)
+	DeleteProject(userID string, name string) error
+	GetTodosByProject(userID string, name string) ([]Todo, error)
+	SetListMapping(mapping map[string]string)
+	GetListMapping() map[string]string
+	Close() error
+}
+
+// openRepository opens the store described by spec, which is the kind
+// of store and its path separated by a colon, like bolt:~/todo/todo.db
+// or sqlite:/tmp/todo.sqlite. An empty spec opens the default bbolt file
+func openRepository(spec string) (Repository, error) {
+	if spec == "" {
+		spec = "bolt:" + getDbPath()
+	}
+
+	kind, path := splitStoreSpec(spec)
+
+	switch kind {
+	case "bolt":
+		// bbolt locks the file, another todo holding it open makes the
+		// open fail after the timeout instead of blocking
+		db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+		if err == bolt.ErrTimeout {
+			return nil, fmt.Errorf("Unable to open %s, it is in use by another todo", path)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		repo := &TodoRepo{}
+		repo.Init(db)
+		err = repo.Migrate()
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+		return repo, nil
+	case "sqlite":
+		db, err := sql.Open("sqlite", path)
+		if err != nil {
+			return nil, err
+		}
+
+		repo := &SQLiteRepo{}
+		err = repo.Init(db)
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+		return repo, nil
+	case "memory":
+		repo := &MemoryRepo{}
+		repo.Init()
+		return repo, nil
+	default:
+		return nil, fmt.Errorf("Unknown store %s, expected bolt:<path>, sqlite:<path> or memory:", spec)
+	}
+}
+
+// uniqueIDs drops repeated IDs keeping the first of each, so that
+// MutateTodos changes every todo once
+func uniqueIDs(ids []string) []string {
+	seen := map[string]bool{}
+	unique := []string{}
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+// splitStoreSpec returns the kind of store and its path, with ~ expanded
+func splitStoreSpec(spec string) (string, string) {
+	parts := strings.SplitN(spec, ":", 2)
+	kind, path := parts[0], ""
+	if len(parts) == 2 {
+		path = expandHome(parts[1])
+	}
+	return kind, path
+}
+
+// sameStore tells if two specs name the same store file. Every memory:
+// spec opens a store of its own
+func sameStore(a string, b string) bool {
+	kindA, pathA := splitStoreSpec(a)
+	kindB, pathB := splitStoreSpec(b)
+	if kindA == "memory" || kindB == "memory" {
+		return false
+	}
+
+	absA, errA := filepath.Abs(pathA)
+	absB, errB := filepath.Abs(pathB)
+	if errA != nil || errB != nil {
+		return filepath.Clean(pathA) == filepath.Clean(pathB)
+	}
+	return absA == absB
+}
+
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err == nil {
+			return home + path[1:]
+		}
+	}
+	return path
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/rs/xid"
+)
+
+// testStores opens an empty store of every kind, closed when the test
+// ends
+func testStores(t *testing.T) map[string]Repository {
+	t.Helper()
+
+	dir := t.TempDir()
+	specs := map[string]string{
+		"memory": "memory:",
+		"bolt":   "bolt:" + filepath.Join(dir, "todo.db"),
+		"sqlite": "sqlite:" + filepath.Join(dir, "todo.sqlite"),
+	}
+
+	stores := map[string]Repository{}
+	for name, spec := range specs {
+		repo, err := openRepository(spec)
+		if err != nil {
+			t.Fatalf("openRepository(%q): %v", spec, err)
+		}
+		t.Cleanup(func() { repo.Close() })
+		stores[name] = repo
+	}
+
+	return stores
+}
+
+func newTestTodo(title string, due time.Time, tags ...string) Todo {
+	if tags == nil {
+		tags = []string{}
+	}
+	return Todo{ID: xid.New().String(), Title: title, Due: due, Tags: tags}
+}
+
+func createTodos(t *testing.T, repo Repository, todos ...Todo) {
+	t.Helper()
+
+	for _, todo := range todos {
+		err := repo.CreateTodo(UserKey, todo)
+		if err != nil {
+			t.Fatalf("CreateTodo(%s): %v", todo.Title, err)
+		}
+	}
+}
+
+func titlesOf(todos []Todo) []string {
+	titles := []string{}
+	for _, todo := range todos {
+		titles = append(titles, todo.Title)
+	}
+	sort.Strings(titles)
+	return titles
+}
+
+func TestSameStore(t *testing.T) {
+	tests := []struct {
+		a, b string
+		same bool
+	}{
+		{"bolt:/tmp/todo.db", "bolt:/tmp/todo.db", true},
+		{"bolt:/tmp/todo.db", "bolt:/tmp/../tmp/./todo.db", true},
+		{"bolt:~/todo/todo.db", "bolt:" + expandHome("~/todo/todo.db"), true},
+		{"bolt:/tmp/todo.db", "sqlite:/tmp/todo.db", true},
+		{"bolt:/tmp/todo.db", "bolt:/tmp/other.db", false},
+		{"memory:", "memory:", false},
+	}
+
+	for _, test := range tests {
+		if got := sameStore(test.a, test.b); got != test.same {
+			t.Errorf("sameStore(%q, %q) = %v, want %v", test.a, test.b, got, test.same)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import "fmt"
+
+// overdueTodos returns the pending todos due on a day before today
+func overdueTodos(repo Repository) ([]Todo, error) {
+	todos, err := repo.GetPendingTodos(UserKey)
+	if err != nil {
+		return nil, err
+	}
+
+	overdue := []Todo{}
+	for _, todo := range todos {
+		if todo.overdue() {
+			overdue = append(overdue, todo)
+		}
+	}
+	return overdue, nil
+}
+
+func rolloverTodos(opts Opts, repo Repository) error {
+	moved, err := rollover(repo)
+	if err != nil {
+		return err
+	}
+
+	if moved == 0 {
+		fmt.Println("No overdue todos")
+		return nil
+	}
+	fmt.Printf("%d overdue todos moved to today\n", moved)
+	return nil
+}
+
+// rollover moves the overdue todos to today, keeping their time of day,
+// and counts the move in their Postponed. The todos are rewritten with
+// MutateTodo so their date index entries move along with the due date
+func rollover(repo Repository) (int, error) {
+	todos, err := overdueTodos(repo)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, todo := range todos {
+		err = repo.MutateTodo(UserKey, todo.ID, func(todo *Todo) error {
+			todo.Due = withClock(today(), todo.dueLocal())
+			todo.Postponed++
+			return nil
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(todos), nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRollover(t *testing.T) {
+	for name, repo := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			yesterday := today().AddDate(0, 0, -1)
+			late := newTestTodo("late", yesterday.AddDate(0, 0, -2))
+			lapsed := newTestTodo("lapsed", yesterday)
+			finished := newTestTodo("finished", yesterday)
+			finished.Done = true
+			current := newTestTodo("current", today())
+			upcoming := newTestTodo("upcoming", today().AddDate(0, 0, 1))
+			createTodos(t, repo, late, lapsed, finished, current, upcoming)
+
+			overdue, err := overdueTodos(repo)
+			if err != nil {
+				t.Fatalf("overdueTodos: %v", err)
+			}
+			if got := titlesOf(overdue); !reflect.DeepEqual(got, []string{"lapsed", "late"}) {
+				t.Errorf("overdueTodos = %v, want [lapsed late]", got)
+			}
+
+			moved, err := rollover(repo)
+			if err != nil {
+				t.Fatalf("rollover: %v", err)
+			}
+			if moved != 2 {
+				t.Errorf("rollover moved %d todos, want 2", moved)
+			}
+
+			todos, err := repo.GetTodosByDate(UserKey, today())
+			if err != nil {
+				t.Fatalf("GetTodosByDate(today): %v", err)
+			}
+			if got := titlesOf(todos); !reflect.DeepEqual(got, []string{"current", "lapsed", "late"}) {
+				t.Errorf("todos due today = %v, want [current lapsed late]", got)
+			}
+
+			todos, err = repo.GetTodosByDate(UserKey, yesterday)
+			if err != nil {
+				t.Fatalf("GetTodosByDate(yesterday): %v", err)
+			}
+			if got := titlesOf(todos); !reflect.DeepEqual(got, []string{"finished"}) {
+				t.Errorf("todos due yesterday = %v, want [finished]", got)
+			}
+
+			got, _ := repo.GetTodo(UserKey, late.ID)
+			if got.Postponed != 1 {
+				t.Errorf("postponed = %d, want 1", got.Postponed)
+			}
+
+			moved, err = rollover(repo)
+			if err != nil || moved != 0 {
+				t.Errorf("rollover again moved %d todos, %v, want none", moved, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// boltMigration upgrades todo.db from one schema version to the next
+type boltMigration struct {
+	description string
+	migrate     func(tx *bolt.Tx) error
+}
+
+// boltMigrations are run in order when todo.db is opened. A database at
+// schema version N has had the first N migrations applied; databases
+// created before versioning have no meta bucket and are at version 0.
+// New fields and index buckets are rolled out by appending a migration,
+// existing ones are never edited
+var boltMigrations = []boltMigration{
+	{
+		description: "record the schema version of the original layout",
+		migrate: func(tx *bolt.Tx) error {
+			return nil
+		},
+	},
+	{
+		description: "build the tag index",
+		migrate: func(tx *bolt.Tx) error {
+			return buildIndex(tx, TagsKey)
+		},
+	},
+	{
+		description: "build the search index",
+		migrate: func(tx *bolt.Tx) error {
+			return buildIndex(tx, SearchKey)
+		},
+	},
+	{
+		description: "store the duration of older todos as their estimate",
+		migrate:     renameDuration,
+	},
+}
+
+// SchemaVersion method returns the schema version stored in the meta
+// bucket, 0 if the database predates versioning
+func (r *TodoRepo) SchemaVersion() (int, error) {
+	version := 0
+
+	err := r.db.View(func(tx *bolt.Tx) error {
+		var err error
+		version, err = schemaVersion(tx)
+		return err
+	})
+
+	return version, err
+}
+
+// Migrate method brings the database up to the latest schema version.
+// Each migration runs in its own transaction together with the version
+// update, so an interrupted upgrade resumes from where it stopped
+func (r *TodoRepo) Migrate() error {
+	version, err := r.SchemaVersion()
+	if err != nil {
+		return err
+	}
+
+	if version > len(boltMigrations) {
+		return fmt.Errorf("Database is at schema version %d, this todo only knows up to version %d",
+			version, len(boltMigrations))
+	}
+
+	for ; version < len(boltMigrations); version++ {
+		migration := boltMigrations[version]
+		err = r.db.Update(func(tx *bolt.Tx) error {
+			err := migration.migrate(tx)
+			if err != nil {
+				return err
+			}
+
+			return setSchemaVersion(tx, version+1)
+		})
+		if err != nil {
+			return fmt.Errorf("Unable to migrate to schema version %d (%s): %v",
+				version+1, migration.description, err)
+		}
+	}
+
+	return nil
+}
+
+func schemaVersion(tx *bolt.Tx) (int, error) {
+	meta := tx.Bucket(MetaKey)
+	if meta == nil {
+		return 0, nil
+	}
+
+	data := meta.Get(VersionKey)
+	if data == nil {
+		return 0, nil
+	}
+
+	version, err := strconv.Atoi(string(data))
+	if err != nil {
+		return 0, fmt.Errorf("Invalid schema version %q in meta bucket", data)
+	}
+
+	return version, nil
+}
+
+func setSchemaVersion(tx *bolt.Tx, version int) error {
+	meta, err := tx.CreateBucketIfNotExists(MetaKey)
+	if err != nil {
+		return err
+	}
+
+	return meta.Put(VersionKey, []byte(strconv.Itoa(version)))
+}
+
+// buildIndex writes the entries of the index bucket named name for the
+// todos of every user. Todos that can't be decoded are skipped and left
+// for fsck to report, so that they don't keep the database from opening
+func buildIndex(tx *bolt.Tx, name []byte) error {
+	return tx.ForEach(func(userID []byte, userBucket *bolt.Bucket) error {
+		if !isUserBucket(userID) {
+			return nil
+		}
+
+		todos := []Todo{}
+		err := userBucket.ForEach(func(k []byte, v []byte) error {
+			if v == nil {
+				return nil
+			}
+
+			todo, err := makeTodo(v)
+			if err != nil {
+				return nil
+			}
+
+			todos = append(todos, todo)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, todo := range todos {
+			for _, e := range indexEntries(todo) {
+				if !bytes.Equal(e.path[0], name) {
+					continue
+				}
+
+				err = putIndexEntry(userBucket, e)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+// renameDuration rewrites the todos that still have the single duration
+// of the layout before the estimate and the actual effort were kept
+// apart. makeTodo reads the duration as the estimate, so writing the
+// todo back stores it under its new name. Todos that can't be decoded
+// are left as they are for fsck to report
+func renameDuration(tx *bolt.Tx) error {
+	return tx.ForEach(func(userID []byte, userBucket *bolt.Bucket) error {
+		if !isUserBucket(userID) {
+			return nil
+		}
+
+		rewritten := map[string][]byte{}
+		err := userBucket.ForEach(func(k []byte, v []byte) error {
+			if v == nil {
+				return nil
+			}
+
+			var fields map[string]json.RawMessage
+			err := json.Unmarshal(v, &fields)
+			if err != nil {
+				return nil
+			}
+			if _, ok := fields["duration"]; !ok {
+				return nil
+			}
+
+			todo, err := makeTodo(v)
+			if err != nil {
+				return nil
+			}
+
+			rewritten[string(k)] = todo.data()
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for k, data := range rewritten {
+			err = userBucket.Put([]byte(k), data)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// writeRawBolt creates a bolt file laid out by fill, bypassing Migrate
+func writeRawBolt(t *testing.T, fill func(tx *bolt.Tx) error) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "todo.db")
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		t.Fatalf("bolt.Open: %v", err)
+	}
+	defer db.Close()
+
+	err = db.Update(fill)
+	if err != nil {
+		t.Fatalf("writing %s: %v", path, err)
+	}
+	return path
+}
+
+func openTestBolt(t *testing.T, path string) *TodoRepo {
+	t.Helper()
+
+	repo, err := openRepository("bolt:" + path)
+	if err != nil {
+		t.Fatalf("openRepository(%s): %v", path, err)
+	}
+	t.Cleanup(func() { repo.Close() })
+	return repo.(*TodoRepo)
+}
+
+func TestMigrateUnversioned(t *testing.T) {
+	todo := newTestTodo("kept", today(), "home")
+	path := writeRawBolt(t, func(tx *bolt.Tx) error {
+		userBucket, err := tx.CreateBucket([]byte(UserKey))
+		if err != nil {
+			return err
+		}
+		err = userBucket.Put(todo.id(), todo.data())
+		if err != nil {
+			return err
+		}
+		return userBucket.Put([]byte("garbled"), []byte("{not json"))
+	})
+
+	repo := openTestBolt(t, path)
+	version, err := repo.SchemaVersion()
+	if err != nil {
+		t.Fatalf("SchemaVersion: %v", err)
+	}
+	if version != len(boltMigrations) {
+		t.Errorf("SchemaVersion = %d, want %d", version, len(boltMigrations))
+	}
+
+	got, err := repo.GetTodo(UserKey, todo.ID)
+	if err != nil {
+		t.Fatalf("GetTodo: %v", err)
+	}
+	if got.Title != "kept" {
+		t.Errorf("GetTodo = %+v, want title kept", got)
+	}
+
+	tagged, err := repo.GetTodosByTag(UserKey, "home")
+	if err != nil {
+		t.Fatalf("GetTodosByTag: %v", err)
+	}
+	if len(tagged) != 1 {
+		t.Errorf("GetTodosByTag(home) = %d todos, want the todo indexed on open", len(tagged))
+	}
+}
+
+func TestMigrateIsIdempotent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "todo.db")
+	repo := openTestBolt(t, path)
+	createTodos(t, repo, newTestTodo("kept", today(), "home"))
+	repo.Close()
+
+	repo = openTestBolt(t, path)
+	err := repo.Migrate()
+	if err != nil {
+		t.Fatalf("Migrate on an up to date database: %v", err)
+	}
+
+	todos, err := repo.GetTodosByTag(UserKey, "home")
+	if err != nil {
+		t.Fatalf("GetTodosByTag: %v", err)
+	}
+	if len(todos) != 1 {
+		t.Errorf("GetTodosByTag(home) = %d todos, want 1", len(todos))
+	}
+}
+
+func TestMigrateRejectsNewerVersion(t *testing.T) {
+	path := writeRawBolt(t, func(tx *bolt.Tx) error {
+		return setSchemaVersion(tx, len(boltMigrations)+1)
+	})
+
+	repo, err := openRepository("bolt:" + path)
+	if err == nil {
+		repo.Close()
+		t.Fatalf("openRepository of a database from a newer todo, want an error")
+	}
+}
+
+func TestRenameDuration(t *testing.T) {
+	todo := newTestTodo("sized", today())
+	var fields map[string]interface{}
+	err := json.Unmarshal(todo.data(), &fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	delete(fields, "estimate")
+	fields["duration"] = 2.5
+	old, err := json.Marshal(fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := writeRawBolt(t, func(tx *bolt.Tx) error {
+		err := setSchemaVersion(tx, 3)
+		if err != nil {
+			return err
+		}
+
+		userBucket, err := tx.CreateBucket([]byte(UserKey))
+		if err != nil {
+			return err
+		}
+		err = userBucket.Put(todo.id(), old)
+		if err != nil {
+			return err
+		}
+		return userBucket.Put([]byte("garbled"), []byte(`{"duration":`))
+	})
+
+	repo := openTestBolt(t, path)
+	err = repo.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket([]byte(UserKey)).Get(todo.id())
+		if bytes.Contains(data, []byte(`"duration"`)) {
+			t.Errorf("todo still stored with a duration: %s", data)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := repo.GetTodo(UserKey, todo.ID)
+	if err != nil {
+		t.Fatalf("GetTodo: %v", err)
+	}
+	if got.Estimate != 2.5 {
+		t.Errorf("estimate = %v, want the duration 2.5", got.Estimate)
+	}
+}
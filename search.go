@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// searchTerm is one arg of todo search. A single word matches todos
+// having it, or with prefix set any word starting with it. Several
+// words are a phrase and have to appear next to each other
+type searchTerm struct {
+	words  []string
+	prefix bool
+}
+
+// tokenize splits text into lower cased words, anything but letters and
+// digits separates words
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// searchTexts returns the texts of the todo that are searched, the
+// title and each note
+func (t Todo) searchTexts() []string {
+	texts := []string{t.Title}
+	for _, note := range t.Notes {
+		texts = append(texts, note.Text)
+	}
+	return texts
+}
+
+// searchWords returns the distinct words of the searched texts, the
+// keys the todo has in the search index
+func (t Todo) searchWords() []string {
+	seen := map[string]bool{}
+	words := []string{}
+	for _, text := range t.searchTexts() {
+		for _, word := range tokenize(text) {
+			if !seen[word] {
+				seen[word] = true
+				words = append(words, word)
+			}
+		}
+	}
+	return words
+}
+
+// hasPhrase tells if one of the searched texts has the words in a row
+func (t Todo) hasPhrase(phrase []string) bool {
+	for _, text := range t.searchTexts() {
+		words := tokenize(text)
+		for i := 0; i+len(phrase) <= len(words); i++ {
+			match := true
+			for j, word := range phrase {
+				if words[i+j] != word {
+					match = false
+					break
+				}
+			}
+			if match {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseSearchQuery reads the args of todo search. Each arg is a term,
+// an arg of several words, given in quotes, is a phrase and a word
+// ending in * matches by prefix
+func parseSearchQuery(args []string) ([]searchTerm, error) {
+	terms := []searchTerm{}
+	for _, arg := range args {
+		prefix := strings.HasSuffix(arg, "*")
+		words := tokenize(strings.TrimSuffix(arg, "*"))
+		if len(words) == 0 {
+			continue
+		}
+
+		if prefix && len(words) > 1 {
+			return nil, fmt.Errorf("Prefix matching works on single words, not on the phrase %q", arg)
+		}
+		terms = append(terms, searchTerm{words: words, prefix: prefix})
+	}
+
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("Usage: todo search <words>, \"a phrase\" or a prefix*")
+	}
+	return terms, nil
+}
+
+func (s searchTerm) String() string {
+	if len(s.words) > 1 {
+		return `"` + strings.Join(s.words, " ") + `"`
+	}
+	if s.prefix {
+		return s.words[0] + "*"
+	}
+	return s.words[0]
+}
+
+// searchTodos returns the todos matching all of the terms, ordered by
+// due date. The first word of each term is looked up in the search
+// index of the store, phrases are then checked against the text of the
+// todos found
+func searchTodos(terms []searchTerm, repo Repository) ([]Todo, error) {
+	var matched map[string]Todo
+	for _, term := range terms {
+		todos, err := repo.GetTodosByWord(UserKey, term.words[0], term.prefix)
+		if err != nil {
+			return nil, err
+		}
+
+		found := map[string]Todo{}
+		for _, todo := range todos {
+			if len(term.words) == 1 || todo.hasPhrase(term.words) {
+				found[todo.ID] = todo
+			}
+		}
+
+		if matched == nil {
+			matched = found
+			continue
+		}
+
+		for id := range matched {
+			if _, ok := found[id]; !ok {
+				delete(matched, id)
+			}
+		}
+	}
+
+	todos := []Todo{}
+	for _, todo := range matched {
+		todos = append(todos, todo)
+	}
+
+	sort.Slice(todos, func(i, j int) bool {
+		if !todos[i].Due.Equal(todos[j].Due) {
+			return todos[i].Due.Before(todos[j].Due)
+		}
+		return todos[i].ID < todos[j].ID
+	})
+
+	return todos, nil
+}
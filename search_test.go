@@ -0,0 +1,79 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSearchQuery(t *testing.T) {
+	tests := []struct {
+		args []string
+		want []searchTerm
+	}{
+		{[]string{"Flat"}, []searchTerm{{words: []string{"flat"}}}},
+		{[]string{"new", "flat"}, []searchTerm{{words: []string{"new"}}, {words: []string{"flat"}}}},
+		{[]string{"new flat"}, []searchTerm{{words: []string{"new", "flat"}}}},
+		{[]string{"fla*"}, []searchTerm{{words: []string{"fla"}, prefix: true}}},
+		{[]string{"flat,", "--"}, []searchTerm{{words: []string{"flat"}}}},
+	}
+
+	for _, test := range tests {
+		got, err := parseSearchQuery(test.args)
+		if err != nil {
+			t.Errorf("parseSearchQuery(%q) returned error: %v", test.args, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("parseSearchQuery(%q) = %+v, want %+v", test.args, got, test.want)
+		}
+	}
+
+	for _, args := range [][]string{{}, {"--"}, {"*"}, {"new fl*"}} {
+		if got, err := parseSearchQuery(args); err == nil {
+			t.Errorf("parseSearchQuery(%q) = %+v, want an error", args, got)
+		}
+	}
+}
+
+func TestSearchTodos(t *testing.T) {
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"flat"}, []string{"Search for a new flat", "Visit the flat"}},
+		{[]string{"FLAT"}, []string{"Search for a new flat", "Visit the flat"}},
+		{[]string{"flat", "new"}, []string{"Search for a new flat"}},
+		{[]string{"new flat"}, []string{"Search for a new flat"}},
+		{[]string{"flat new"}, []string{}},
+		{[]string{"vis*"}, []string{"Visit the flat"}},
+		{[]string{"landlord"}, []string{"Visit the flat"}},
+		{[]string{"deposit back"}, []string{"Visit the flat"}},
+		{[]string{"fl"}, []string{}},
+		{[]string{"house"}, []string{}},
+	}
+
+	for name, repo := range testStores(t) {
+		visit := newTestTodo("Visit the flat", today())
+		visit.Notes = []Note{{Text: "Ask the landlord for the deposit back"}}
+		createTodos(t, repo,
+			newTestTodo("Search for a new flat", today()),
+			visit,
+			newTestTodo("Buy new shoes", today().AddDate(0, 0, 1)),
+		)
+
+		for _, test := range tests {
+			terms, err := parseSearchQuery(test.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			todos, err := searchTodos(terms, repo)
+			if err != nil {
+				t.Fatalf("%s: searchTodos(%q): %v", name, test.args, err)
+			}
+			if got := titlesOf(todos); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("%s: searchTodos(%q) = %v, want %v", name, test.args, got, test.want)
+			}
+		}
+	}
+}
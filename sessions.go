@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Session struct is a stretch of work logged against a todo with the
+// start and stop commands. A session that is still running has no End
+type Session struct {
+	TodoID string    `json:"todo"`
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end,omitempty"`
+}
+
+func (s Session) running() bool {
+	return s.End.IsZero()
+}
+
+// hours returns the length of the session, a running session counts up
+// to now
+func (s Session) hours(now time.Time) float32 {
+	end := s.End
+	if s.running() {
+		end = now
+	}
+	return float32(end.Sub(s.Start).Hours())
+}
+
+// key orders the sessions of a todo by start time in the bbolt store
+func (s Session) key() []byte {
+	return []byte(s.Start.UTC().Format("20060102T150405.000000000"))
+}
+
+func (s Session) data() []byte {
+	data, _ := json.Marshal(s)
+	return data
+}
+
+func makeSession(data []byte) (Session, error) {
+	var s Session
+	err := json.Unmarshal(data, &s)
+	return s, err
+}
+
+// spentHours adds up the logged sessions, a running session counts up
+// to now
+func spentHours(sessions []Session) float32 {
+	now := time.Now()
+	spent := float32(0.0)
+	for _, s := range sessions {
+		spent += s.hours(now)
+	}
+	return spent
+}
+
+// spentByTodo returns the hours logged against each of the todos
+func spentByTodo(todos []Todo, repo Repository) (map[string]float32, error) {
+	spent := map[string]float32{}
+	for _, todo := range todos {
+		sessions, err := repo.GetSessions(UserKey, todo.ID)
+		if err != nil {
+			return nil, err
+		}
+		spent[todo.ID] = spentHours(sessions)
+	}
+	return spent, nil
+}
+
+func startTimer(opts Opts, repo Repository) error {
+	id, err := idFromOpts(opts, repo)
+	if err != nil {
+		return err
+	}
+	todo, err := repo.GetTodo(UserKey, id)
+	if err != nil {
+		return err
+	}
+
+	running, err := repo.GetRunningSession(UserKey)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if running != nil {
+		if running.TodoID == todo.ID {
+			return fmt.Errorf("Timer is already running for %s", todo.Title)
+		}
+
+		err = stopRunningTimer(repo, now)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = repo.AddSession(UserKey, Session{TodoID: todo.ID, Start: now})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Started timer for %s\n", todo.Title)
+	return nil
+}
+
+func stopTimer(opts Opts, repo Repository) error {
+	return stopRunningTimer(repo, time.Now())
+}
+
+// stopRunningTimer ends the running session and reports the time spent
+// on its todo, which is worked out from its sessions
+func stopRunningTimer(repo Repository, now time.Time) error {
+	session, err := repo.StopSession(UserKey, now)
+	if err != nil {
+		return err
+	}
+
+	todo, err := repo.GetTodo(UserKey, session.TodoID)
+	if err != nil {
+		return err
+	}
+
+	sessions, err := repo.GetSessions(UserKey, session.TodoID)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Stopped timer for %s after %.1f hours, %.1f of %.1f estimated hours spent\n",
+		todo.Title, session.hours(now), spentHours(sessions), todo.Estimate)
+	return nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSpentHours(t *testing.T) {
+	start := time.Now().Add(-3 * time.Hour)
+	sessions := []Session{
+		{Start: start, End: start.Add(90 * time.Minute)},
+		{Start: start.Add(2 * time.Hour)},
+	}
+
+	// The running session counts up to now, give it a minute of slack
+	if got := spentHours(sessions); got < 2.5 || got > 2.52 {
+		t.Errorf("spentHours = %v, want 2.5", got)
+	}
+}
+
+func TestTimers(t *testing.T) {
+	for name, repo := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			first := newTestTodo("first", today())
+			second := newTestTodo("second", today())
+			createTodos(t, repo, first, second)
+
+			err := runCommands(t, repo, first, "todo 1 start")
+			if err != nil {
+				t.Fatalf("starting the first timer: %v", err)
+			}
+			if err = runCommands(t, repo, first, "todo 1 start"); err == nil {
+				t.Errorf("starting a running timer again, want an error")
+			}
+
+			// Starting another timer stops the running one
+			err = runCommands(t, repo, second, "todo 1 start")
+			if err != nil {
+				t.Fatalf("starting the second timer: %v", err)
+			}
+			running, err := repo.GetRunningSession(UserKey)
+			if err != nil || running == nil || running.TodoID != second.ID {
+				t.Fatalf("running session = %+v, %v, want one for the second todo", running, err)
+			}
+
+			err = runCommands(t, repo, second, "todo stop")
+			if err != nil {
+				t.Fatalf("stopping the timer: %v", err)
+			}
+			running, err = repo.GetRunningSession(UserKey)
+			if err != nil || running != nil {
+				t.Errorf("running session after stop = %+v, %v, want none", running, err)
+			}
+			if err = runCommands(t, repo, second, "todo stop"); err == nil {
+				t.Errorf("stopping with no timer running, want an error")
+			}
+
+			for _, todo := range []Todo{first, second} {
+				sessions, err := repo.GetSessions(UserKey, todo.ID)
+				if err != nil {
+					t.Fatalf("GetSessions(%s): %v", todo.Title, err)
+				}
+				if len(sessions) != 1 || sessions[0].running() {
+					t.Errorf("sessions of %s = %+v, want one stopped session", todo.Title, sessions)
+				}
+			}
+		})
+	}
+}
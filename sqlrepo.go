@@ -0,0 +1,833 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// sqliteMigrations are the statements building the SQLite schema. The
+// number applied so far is kept in the user_version pragma, new schema
+// changes are appended here and never edited in place
+var sqliteMigrations = []string{
+	`CREATE TABLE todos (
+		user_id TEXT NOT NULL,
+		id      TEXT NOT NULL,
+		title   TEXT NOT NULL,
+		due     TEXT NOT NULL,
+		done    INTEGER NOT NULL,
+		effort  REAL NOT NULL,
+		data    TEXT NOT NULL,
+		PRIMARY KEY (user_id, id)
+	)`,
+	`CREATE INDEX todos_due ON todos (user_id, due)`,
+	`CREATE INDEX todos_done ON todos (user_id, done)`,
+	`CREATE TABLE todo_tags (
+		user_id TEXT NOT NULL,
+		todo_id TEXT NOT NULL,
+		tag     TEXT NOT NULL,
+		PRIMARY KEY (user_id, todo_id, tag)
+	)`,
+	`CREATE INDEX todo_tags_tag ON todo_tags (user_id, tag)`,
+	`CREATE TABLE listing (
+		key TEXT PRIMARY KEY,
+		id  TEXT NOT NULL
+	)`,
+	`ALTER TABLE todos ADD COLUMN completed TEXT NOT NULL DEFAULT ''`,
+	`CREATE INDEX todos_completed ON todos (user_id, completed)`,
+	`CREATE TABLE sessions (
+		user_id TEXT NOT NULL,
+		todo_id TEXT NOT NULL,
+		started TEXT NOT NULL,
+		stopped TEXT NOT NULL DEFAULT '',
+		PRIMARY KEY (user_id, todo_id, started)
+	)`,
+	`CREATE INDEX sessions_stopped ON sessions (user_id, stopped)`,
+	`CREATE TABLE projects (
+		user_id TEXT NOT NULL,
+		name    TEXT NOT NULL,
+		data    TEXT NOT NULL,
+		PRIMARY KEY (user_id, name)
+	)`,
+	`ALTER TABLE todos ADD COLUMN project TEXT NOT NULL DEFAULT ''`,
+	`CREATE INDEX todos_project ON todos (user_id, project)`,
+	`CREATE TABLE todo_words (
+		user_id TEXT NOT NULL,
+		todo_id TEXT NOT NULL,
+		word    TEXT NOT NULL,
+		PRIMARY KEY (user_id, todo_id, word)
+	)`,
+	`CREATE INDEX todo_words_word ON todo_words (user_id, word)`,
+}
+
+// sqliteBackfills fill in data for a schema change that SQL alone can't
+// compute, keyed by the schema version they complete. They run in the
+// transaction of that version's statement
+var sqliteBackfills = map[int]func(tx *sql.Tx) error{
+	15: backfillWords,
+}
+
+// SQLiteRepo struct is the Repository backed by a SQLite database. Each
+// todo is a row of the todos table with due, done and tags available as
+// indexed columns, the full todo is kept as JSON in the data column
+type SQLiteRepo struct {
+	db *sql.DB
+}
+
+// Init method
+func (r *SQLiteRepo) Init(db *sql.DB) error {
+	r.db = db
+
+	var version int
+	err := db.QueryRow("PRAGMA user_version").Scan(&version)
+	if err != nil {
+		return err
+	}
+
+	for ; version < len(sqliteMigrations); version++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(sqliteMigrations[version])
+		if backfill := sqliteBackfills[version+1]; err == nil && backfill != nil {
+			err = backfill(tx)
+		}
+		if err == nil {
+			_, err = tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", version+1))
+		}
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("Unable to migrate SQLite schema to version %d: %v", version+1, err)
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close method
+func (r *SQLiteRepo) Close() error {
+	return r.db.Close()
+}
+
+// CreateTodo method
+func (r *SQLiteRepo) CreateTodo(userID string, t Todo) error {
+	prepareWrite(nil, &t, time.Now())
+	return r.inTx(func(tx *sql.Tx) error {
+		return putTodoRow(tx, userID, t)
+	})
+}
+
+// GetPendingTodos method
+func (r *SQLiteRepo) GetPendingTodos(userID string) ([]Todo, error) {
+	return r.query("SELECT data FROM todos WHERE user_id = ? AND done = 0 ORDER BY id", userID)
+}
+
+// GetTodosByDate method
+func (r *SQLiteRepo) GetTodosByDate(userID string, date time.Time) ([]Todo, error) {
+	return r.query("SELECT data FROM todos WHERE user_id = ? AND due = ? ORDER BY id",
+		userID, date.Format("2006-01-02"))
+}
+
+// GetTodosInRange method
+func (r *SQLiteRepo) GetTodosInRange(userID string, from time.Time, to time.Time) ([]Todo, error) {
+	return r.query("SELECT data FROM todos WHERE user_id = ? AND due BETWEEN ? AND ? ORDER BY due, id",
+		userID, from.Format("2006-01-02"), to.Format("2006-01-02"))
+}
+
+// GetAllTodos method
+func (r *SQLiteRepo) GetAllTodos(userID string) ([]Todo, error) {
+	return r.query("SELECT data FROM todos WHERE user_id = ? ORDER BY id", userID)
+}
+
+// ForEachTodo method calls fn with every todo of the user in ID order,
+// one row at a time, stopping at the first error
+func (r *SQLiteRepo) ForEachTodo(userID string, fn func(Todo) error) error {
+	rows, err := r.db.Query("SELECT data FROM todos WHERE user_id = ? ORDER BY id", userID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var data []byte
+		err = rows.Scan(&data)
+		if err != nil {
+			return err
+		}
+
+		todo, err := makeTodo(data)
+		if err != nil {
+			return err
+		}
+
+		err = fn(todo)
+		if err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// GetTodosCompletedInRange method
+func (r *SQLiteRepo) GetTodosCompletedInRange(userID string, from time.Time, to time.Time) ([]Todo, error) {
+	todos, err := r.query("SELECT data FROM todos WHERE user_id = ? AND done = 1 AND completed BETWEEN ? AND ?",
+		userID, from.Format("2006-01-02"), to.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+
+	return completedInRange(todos, from, to), nil
+}
+
+// GetTodosByTag method
+func (r *SQLiteRepo) GetTodosByTag(userID string, tag string) ([]Todo, error) {
+	return r.query(`SELECT data FROM todos WHERE user_id = ? AND id IN
+		(SELECT todo_id FROM todo_tags WHERE user_id = ? AND tag = ?) ORDER BY id`, userID, userID, tag)
+}
+
+// GetTags method
+func (r *SQLiteRepo) GetTags(userID string) ([]string, error) {
+	tags := []string{}
+
+	rows, err := r.db.Query("SELECT DISTINCT tag FROM todo_tags WHERE user_id = ? AND tag != '' ORDER BY tag", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tag string
+		err = rows.Scan(&tag)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+
+	return tags, rows.Err()
+}
+
+// GetTodosByWord method
+func (r *SQLiteRepo) GetTodosByWord(userID string, word string, prefix bool) ([]Todo, error) {
+	if prefix {
+		// The words are matched as text ranges rather than with LIKE,
+		// which would read _ and % in the word as wildcards
+		return r.query(`SELECT data FROM todos WHERE user_id = ? AND id IN
+			(SELECT todo_id FROM todo_words WHERE user_id = ? AND word >= ? AND word < ?) ORDER BY id`,
+			userID, userID, word, word+"\U0010FFFF")
+	}
+
+	return r.query(`SELECT data FROM todos WHERE user_id = ? AND id IN
+		(SELECT todo_id FROM todo_words WHERE user_id = ? AND word = ?) ORDER BY id`, userID, userID, word)
+}
+
+// GetUsers method
+func (r *SQLiteRepo) GetUsers() ([]string, error) {
+	users := []string{}
+
+	rows, err := r.db.Query("SELECT DISTINCT user_id FROM todos ORDER BY user_id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var userID string
+		err = rows.Scan(&userID)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, userID)
+	}
+
+	return users, rows.Err()
+}
+
+// GetTodo method
+func (r *SQLiteRepo) GetTodo(userID string, id string) (Todo, error) {
+	var data []byte
+	err := r.db.QueryRow("SELECT data FROM todos WHERE user_id = ? AND id = ?", userID, id).Scan(&data)
+	if err == sql.ErrNoRows {
+		return Todo{}, fmt.Errorf("No todo found for ID: %s", id)
+	}
+	if err != nil {
+		return Todo{}, err
+	}
+
+	return makeTodo(data)
+}
+
+// SetTodoDone method, completing a recurring todo also creates its next
+// occurrence
+func (r *SQLiteRepo) SetTodoDone(userID string, todoID string, status bool) error {
+	return r.MutateAndCreateTodo(userID, todoID, func(todo *Todo) (*Todo, error) {
+		return todo.markDone(status)
+	})
+}
+
+// SetTodoEstimate method
+func (r *SQLiteRepo) SetTodoEstimate(userID string, todoID string, estimate float32) error {
+	return r.MutateTodo(userID, todoID, func(todo *Todo) error {
+		todo.Estimate = estimate
+		return nil
+	})
+}
+
+// SetTodoDue method
+func (r *SQLiteRepo) SetTodoDue(userID string, todoID string, due time.Time) error {
+	return r.MutateTodo(userID, todoID, func(todo *Todo) error {
+		todo.Due = due
+		return nil
+	})
+}
+
+// SetTodoTags method
+func (r *SQLiteRepo) SetTodoTags(userID string, todoID string, tags []string) error {
+	return r.MutateTodo(userID, todoID, func(todo *Todo) error {
+		todo.Tags = tags
+		return nil
+	})
+}
+
+// DeleteTodo method
+func (r *SQLiteRepo) DeleteTodo(userID string, todoID string) error {
+	return r.inTx(func(tx *sql.Tx) error {
+		return deleteTodoRow(tx, userID, todoID)
+	})
+}
+
+// UpdateTodo method
+func (r *SQLiteRepo) UpdateTodo(userID string, todoID string, todo Todo) error {
+	return r.MutateTodo(userID, todoID, func(t *Todo) error {
+		*t = todo
+		return nil
+	})
+}
+
+// SetListMapping method replaces the mapping of the previous listing
+func (r *SQLiteRepo) SetListMapping(mapping map[string]string) {
+	r.inTx(func(tx *sql.Tx) error {
+		_, err := tx.Exec("DELETE FROM listing")
+		if err != nil {
+			return err
+		}
+
+		for k, v := range mapping {
+			_, err := tx.Exec("INSERT OR REPLACE INTO listing (key, id) VALUES (?, ?)", k, v)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// GetListMapping method
+func (r *SQLiteRepo) GetListMapping() map[string]string {
+	listMapping := map[string]string{}
+
+	rows, err := r.db.Query("SELECT key, id FROM listing")
+	if err != nil {
+		return listMapping
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var k, v string
+		if rows.Scan(&k, &v) == nil {
+			listMapping[k] = v
+		}
+	}
+
+	return listMapping
+}
+
+func (r *SQLiteRepo) query(query string, args ...interface{}) ([]Todo, error) {
+	todos := []Todo{}
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var data []byte
+		err = rows.Scan(&data)
+		if err != nil {
+			return nil, err
+		}
+
+		todo, err := makeTodo(data)
+		if err != nil {
+			return nil, err
+		}
+
+		todos = append(todos, todo)
+	}
+
+	return todos, rows.Err()
+}
+
+// MutateTodo method
+func (r *SQLiteRepo) MutateTodo(userID string, todoID string, change func(*Todo) error) error {
+	return r.MutateAndCreateTodo(userID, todoID, func(todo *Todo) (*Todo, error) {
+		return nil, change(todo)
+	})
+}
+
+// MutateAndCreateTodo method is MutateTodo where change can also
+// return a new todo to be created in the same transaction
+func (r *SQLiteRepo) MutateAndCreateTodo(userID string, todoID string, change func(*Todo) (*Todo, error)) error {
+	return r.inTx(func(tx *sql.Tx) error {
+		return mutateTodoRow(tx, userID, todoID, change)
+	})
+}
+
+// MutateTodos method applies change to each of the todos in a single
+// transaction, none of them is written if change fails for any. A todo
+// listed more than once is changed once
+func (r *SQLiteRepo) MutateTodos(userID string, todoIDs []string, change func(*Todo) error) error {
+	return r.inTx(func(tx *sql.Tx) error {
+		return mutateTodoRows(tx, userID, uniqueIDs(todoIDs), change)
+	})
+}
+
+// ReplaceTag method
+func (r *SQLiteRepo) ReplaceTag(userID string, from string, to string, merge bool) (int, error) {
+	count := 0
+
+	err := r.inTx(func(tx *sql.Tx) error {
+		tagged, err := taggedRowIDs(tx, userID, from)
+		if err != nil {
+			return err
+		}
+
+		existing, err := taggedRowIDs(tx, userID, to)
+		if err != nil {
+			return err
+		}
+
+		err = checkReplaceTag(from, to, merge, len(tagged), len(existing))
+		if err != nil {
+			return err
+		}
+
+		count = len(tagged)
+		return mutateTodoRows(tx, userID, tagged, func(todo *Todo) error {
+			todo.replaceTag(from, to)
+			return nil
+		})
+	})
+
+	return count, err
+}
+
+func taggedRowIDs(tx *sql.Tx, userID string, tag string) ([]string, error) {
+	rows, err := tx.Query("SELECT DISTINCT todo_id FROM todo_tags WHERE user_id = ? AND tag = ? ORDER BY todo_id", userID, tag)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		err = rows.Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+func mutateTodoRows(tx *sql.Tx, userID string, todoIDs []string, change func(*Todo) error) error {
+	for _, todoID := range todoIDs {
+		err := mutateTodoRow(tx, userID, todoID, func(todo *Todo) (*Todo, error) {
+			return nil, change(todo)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func mutateTodoRow(tx *sql.Tx, userID string, todoID string, change func(*Todo) (*Todo, error)) error {
+	var data []byte
+	err := tx.QueryRow("SELECT data FROM todos WHERE user_id = ? AND id = ?", userID, todoID).Scan(&data)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("No todo found for ID: %s", todoID)
+	}
+	if err != nil {
+		return err
+	}
+
+	old, err := makeTodo(data)
+	if err != nil {
+		return err
+	}
+	todo, err := makeTodo(data)
+	if err != nil {
+		return err
+	}
+
+	created, err := change(&todo)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	prepareWrite(&old, &todo, now)
+	if created != nil {
+		prepareWrite(nil, created, now)
+	}
+
+	if todo.ID != todoID {
+		err = deleteTodoRow(tx, userID, todoID)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = putTodoRow(tx, userID, todo)
+	if err != nil || created == nil {
+		return err
+	}
+
+	return putTodoRow(tx, userID, *created)
+}
+
+func (r *SQLiteRepo) inTx(fn func(*sql.Tx) error) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	err = fn(tx)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// putTodoRow writes the todo row and replaces its tag rows
+func putTodoRow(tx *sql.Tx, userID string, t Todo) error {
+	completed := ""
+	if !t.Completed.IsZero() {
+		completed = t.Completed.Local().Format("2006-01-02")
+	}
+
+	_, err := tx.Exec(`INSERT OR REPLACE INTO todos (user_id, id, title, due, done, effort, completed, project, data)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		userID, t.ID, t.Title, string(t.due()), t.Done, t.Estimate, completed, t.Project, string(t.data()))
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM todo_tags WHERE user_id = ? AND todo_id = ?", userID, t.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, tag := range t.Tags {
+		_, err = tx.Exec("INSERT OR IGNORE INTO todo_tags (user_id, todo_id, tag) VALUES (?, ?, ?)", userID, t.ID, tag)
+		if err != nil {
+			return err
+		}
+	}
+
+	return putWordRows(tx, userID, t)
+}
+
+// putWordRows replaces the rows of the todo in the todo_words table, the
+// search index of the SQLite store
+func putWordRows(tx *sql.Tx, userID string, t Todo) error {
+	_, err := tx.Exec("DELETE FROM todo_words WHERE user_id = ? AND todo_id = ?", userID, t.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, word := range t.searchWords() {
+		_, err = tx.Exec("INSERT INTO todo_words (user_id, todo_id, word) VALUES (?, ?, ?)", userID, t.ID, word)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// backfillWords indexes the words of the todos stored before there was
+// a todo_words table
+func backfillWords(tx *sql.Tx) error {
+	rows, err := tx.Query("SELECT user_id, data FROM todos")
+	if err != nil {
+		return err
+	}
+
+	type row struct {
+		userID string
+		todo   Todo
+	}
+	todos := []row{}
+	for rows.Next() {
+		var userID string
+		var data []byte
+		err = rows.Scan(&userID, &data)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+
+		todo, err := makeTodo(data)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		todos = append(todos, row{userID, todo})
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for _, r := range todos {
+		err = putWordRows(tx, r.userID, r.todo)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func deleteTodoRow(tx *sql.Tx, userID string, todoID string) error {
+	result, err := tx.Exec("DELETE FROM todos WHERE user_id = ? AND id = ?", userID, todoID)
+	if err != nil {
+		return err
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("No todo found for ID: %s", todoID)
+	}
+
+	_, err = tx.Exec("DELETE FROM todo_tags WHERE user_id = ? AND todo_id = ?", userID, todoID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM sessions WHERE user_id = ? AND todo_id = ?", userID, todoID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM todo_words WHERE user_id = ? AND todo_id = ?", userID, todoID)
+	return err
+}
+
+// sessionTime is how session times are kept in the sessions table, in
+// UTC so that they sort as text
+const sessionTime = "2006-01-02T15:04:05.000000000Z"
+
+// AddSession method
+func (r *SQLiteRepo) AddSession(userID string, session Session) error {
+	return r.inTx(func(tx *sql.Tx) error {
+		var count int
+		err := tx.QueryRow("SELECT COUNT(*) FROM todos WHERE user_id = ? AND id = ?", userID, session.TodoID).Scan(&count)
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return fmt.Errorf("No todo found for ID: %s", session.TodoID)
+		}
+
+		stopped := ""
+		if session.running() {
+			var todoID string
+			err = tx.QueryRow("SELECT todo_id FROM sessions WHERE user_id = ? AND stopped = ''", userID).Scan(&todoID)
+			if err == nil {
+				return fmt.Errorf("A timer is already running for todo %s", todoID)
+			}
+			if err != sql.ErrNoRows {
+				return err
+			}
+		} else {
+			stopped = session.End.UTC().Format(sessionTime)
+		}
+
+		_, err = tx.Exec("INSERT INTO sessions (user_id, todo_id, started, stopped) VALUES (?, ?, ?, ?)",
+			userID, session.TodoID, session.Start.UTC().Format(sessionTime), stopped)
+		return err
+	})
+}
+
+// StopSession method
+func (r *SQLiteRepo) StopSession(userID string, end time.Time) (Session, error) {
+	var session Session
+	err := r.inTx(func(tx *sql.Tx) error {
+		var started string
+		err := tx.QueryRow("SELECT todo_id, started FROM sessions WHERE user_id = ? AND stopped = ''", userID).
+			Scan(&session.TodoID, &started)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("No timer is running")
+		}
+		if err != nil {
+			return err
+		}
+
+		session.Start, err = time.Parse(sessionTime, started)
+		if err != nil {
+			return err
+		}
+		session.End = end
+
+		_, err = tx.Exec("UPDATE sessions SET stopped = ? WHERE user_id = ? AND todo_id = ? AND started = ?",
+			end.UTC().Format(sessionTime), userID, session.TodoID, started)
+		return err
+	})
+
+	return session, err
+}
+
+// GetRunningSession method
+func (r *SQLiteRepo) GetRunningSession(userID string) (*Session, error) {
+	sessions, err := r.querySessions("SELECT todo_id, started, stopped FROM sessions WHERE user_id = ? AND stopped = ''", userID)
+	if err != nil || len(sessions) == 0 {
+		return nil, err
+	}
+
+	return &sessions[0], nil
+}
+
+// GetSessions method
+func (r *SQLiteRepo) GetSessions(userID string, todoID string) ([]Session, error) {
+	return r.querySessions("SELECT todo_id, started, stopped FROM sessions WHERE user_id = ? AND todo_id = ? ORDER BY started",
+		userID, todoID)
+}
+
+func (r *SQLiteRepo) querySessions(query string, args ...interface{}) ([]Session, error) {
+	sessions := []Session{}
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var session Session
+		var started, stopped string
+		err = rows.Scan(&session.TodoID, &started, &stopped)
+		if err != nil {
+			return nil, err
+		}
+
+		session.Start, err = time.Parse(sessionTime, started)
+		if err != nil {
+			return nil, err
+		}
+		if stopped != "" {
+			session.End, err = time.Parse(sessionTime, stopped)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	return sessions, rows.Err()
+}
+
+// SaveProject method
+func (r *SQLiteRepo) SaveProject(userID string, project Project) error {
+	_, err := r.db.Exec("INSERT OR REPLACE INTO projects (user_id, name, data) VALUES (?, ?, ?)",
+		userID, project.Name, string(project.data()))
+	return err
+}
+
+// GetProject method
+func (r *SQLiteRepo) GetProject(userID string, name string) (Project, error) {
+	var data []byte
+	err := r.db.QueryRow("SELECT data FROM projects WHERE user_id = ? AND name = ?", userID, name).Scan(&data)
+	if err == sql.ErrNoRows {
+		return Project{}, fmt.Errorf("No project found named %s", name)
+	}
+	if err != nil {
+		return Project{}, err
+	}
+
+	return makeProject(data)
+}
+
+// GetProjects method
+func (r *SQLiteRepo) GetProjects(userID string) ([]Project, error) {
+	projects := []Project{}
+
+	rows, err := r.db.Query("SELECT data FROM projects WHERE user_id = ? ORDER BY name", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var data []byte
+		err = rows.Scan(&data)
+		if err != nil {
+			return nil, err
+		}
+
+		project, err := makeProject(data)
+		if err != nil {
+			return nil, err
+		}
+
+		projects = append(projects, project)
+	}
+
+	return projects, rows.Err()
+}
+
+// DeleteProject method
+func (r *SQLiteRepo) DeleteProject(userID string, name string) error {
+	result, err := r.db.Exec("DELETE FROM projects WHERE user_id = ? AND name = ?", userID, name)
+	if err != nil {
+		return err
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("No project found named %s", name)
+	}
+
+	return nil
+}
+
+// GetTodosByProject method
+func (r *SQLiteRepo) GetTodosByProject(userID string, name string) ([]Todo, error) {
+	return r.query("SELECT data FROM todos WHERE user_id = ? AND project = ? ORDER BY id", userID, name)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+func openTestSQLite(t *testing.T, path string) *SQLiteRepo {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("sql.Open: %v", err)
+	}
+
+	repo := &SQLiteRepo{}
+	err = repo.Init(db)
+	if err != nil {
+		db.Close()
+		t.Fatalf("Init: %v", err)
+	}
+	return repo
+}
+
+func TestSQLiteInit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "todo.sqlite")
+
+	repo := openTestSQLite(t, path)
+	var version int
+	err := repo.db.QueryRow("PRAGMA user_version").Scan(&version)
+	if err != nil {
+		t.Fatalf("PRAGMA user_version: %v", err)
+	}
+	if version != len(sqliteMigrations) {
+		t.Errorf("user_version = %d, want %d", version, len(sqliteMigrations))
+	}
+
+	todo := newTestTodo("kept", today(), "home")
+	createTodos(t, repo, todo)
+	repo.Close()
+
+	repo = openTestSQLite(t, path)
+	defer repo.Close()
+
+	got, err := repo.GetTodo(UserKey, todo.ID)
+	if err != nil {
+		t.Fatalf("GetTodo after reopening: %v", err)
+	}
+	if got.Title != "kept" {
+		t.Errorf("todo after reopening = %+v, want title kept", got)
+	}
+
+	tagged, err := repo.GetTodosByTag(UserKey, "home")
+	if err != nil {
+		t.Fatalf("GetTodosByTag: %v", err)
+	}
+	if len(tagged) != 1 {
+		t.Errorf("GetTodosByTag(home) = %d todos, want 1", len(tagged))
+	}
+}
+
+func TestSQLiteBackfillWords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "todo.sqlite")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("sql.Open: %v", err)
+	}
+
+	// A store from before the search index, holding one todo
+	todo := newTestTodo("Call the plumber", today())
+	for _, statement := range sqliteMigrations[:14] {
+		_, err = db.Exec(statement)
+		if err != nil {
+			t.Fatalf("building the version 14 schema: %v", err)
+		}
+	}
+	_, err = db.Exec("PRAGMA user_version = 14")
+	if err == nil {
+		_, err = db.Exec(`INSERT INTO todos (user_id, id, title, due, done, effort, data)
+			VALUES (?, ?, ?, ?, 0, 0, ?)`, UserKey, todo.ID, todo.Title, string(todo.due()), string(todo.data()))
+	}
+	if err != nil {
+		t.Fatalf("writing the version 14 store: %v", err)
+	}
+	db.Close()
+
+	repo := openTestSQLite(t, path)
+	defer repo.Close()
+
+	todos, err := repo.GetTodosByWord(UserKey, "plumber", false)
+	if err != nil {
+		t.Fatalf("GetTodosByWord: %v", err)
+	}
+	if got := titlesOf(todos); len(got) != 1 || got[0] != todo.Title {
+		t.Errorf("GetTodosByWord(plumber) = %v, want the todo stored before the index", got)
+	}
+}
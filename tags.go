@@ -0,0 +1,288 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// todosByTags returns the todos matching a tag query, the todos having
+// any of the tags of each group, ordered by due date. The todos of each
+// group come from the tag index of the store
+func todosByTags(query [][]string, repo Repository) ([]Todo, error) {
+	var matched map[string]Todo
+	for _, tags := range query {
+		group := map[string]Todo{}
+		for _, tag := range tags {
+			todos, err := repo.GetTodosByTag(UserKey, tag)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, todo := range todos {
+				group[todo.ID] = todo
+			}
+		}
+
+		if matched == nil {
+			matched = group
+			continue
+		}
+
+		for id := range matched {
+			if _, ok := group[id]; !ok {
+				delete(matched, id)
+			}
+		}
+	}
+
+	todos := []Todo{}
+	for _, todo := range matched {
+		todos = append(todos, todo)
+	}
+
+	sort.Slice(todos, func(i, j int) bool {
+		if !todos[i].Due.Equal(todos[j].Due) {
+			return todos[i].Due.Before(todos[j].Due)
+		}
+		return todos[i].ID < todos[j].ID
+	})
+
+	return todos, nil
+}
+
+// showTags prints every tag in use with the number of todos having it,
+// how many of them are pending and their planned and spent hours
+func showTags(opts Opts, repo Repository) error {
+	tags, err := repo.GetTags(UserKey)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\nTags\n----\n")
+	for _, tag := range tags {
+		todos, err := repo.GetTodosByTag(UserKey, tag)
+		if err != nil {
+			return err
+		}
+
+		hours, err := spentByTodo(todos, repo)
+		if err != nil {
+			return err
+		}
+
+		pending, planned, spent := 0, float32(0.0), float32(0.0)
+		for _, todo := range todos {
+			if !todo.Done {
+				pending++
+			}
+			planned += todo.Estimate
+			spent += hours[todo.ID]
+		}
+
+		fmt.Printf("#%-15s %3d todos, %3d pending, %.1fh planned / %.1fh spent\n",
+			tag, len(todos), pending, planned, spent)
+	}
+	fmt.Println()
+
+	return nil
+}
+
+var indexListRe = regexp.MustCompile(`^\d+(,\d+)*$`)
+
+// isTagChange reads +#tag and -#tag, or lists like +#work,#urgent, as
+// tags to add to or remove from the todo
+func isTagChange(param string) (bool, bool, []string) {
+	if !strings.HasPrefix(param, "+#") && !strings.HasPrefix(param, "-#") {
+		return false, false, nil
+	}
+
+	isTags, tags := isTags(param[1:])
+	if !isTags {
+		return false, false, nil
+	}
+
+	for _, tag := range tags {
+		if tag == "" || strings.ContainsAny(tag, "#") {
+			return false, false, nil
+		}
+	}
+
+	return true, param[0] == '+', tags
+}
+
+func areTagChanges(params []string) bool {
+	for _, param := range params {
+		if isTagChange, _, _ := isTagChange(param); !isTagChange {
+			return false
+		}
+	}
+	return true
+}
+
+// fillInTagChange adds the tags of a +#tag or -#tag param to the tags
+// the opts add or remove
+func fillInTagChange(param string, opts *Opts) bool {
+	isTagChange, add, tags := isTagChange(param)
+	if !isTagChange {
+		return false
+	}
+
+	key := "removeTags"
+	if add {
+		key = "addTags"
+	}
+
+	existing, _ := opts.params[key].([]string)
+	opts.params[key] = append(existing, tags...)
+	return true
+}
+
+// applyTagChanges adds and removes the tags given with +#tag and -#tag,
+// the other tags of the todo are kept
+func applyTagChanges(todo *Todo, opts Opts) {
+	if addi, present := opts.params["addTags"]; present {
+		for _, tag := range addi.([]string) {
+			if !hasTag(todo.Tags, tag) {
+				todo.Tags = append(todo.Tags, tag)
+			}
+		}
+	}
+
+	if removei, present := opts.params["removeTags"]; present {
+		for _, tag := range removei.([]string) {
+			todo.Tags = withoutTag(todo.Tags, tag)
+		}
+	}
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func withoutTag(tags []string, tag string) []string {
+	kept := []string{}
+	for _, t := range tags {
+		if t != tag {
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}
+
+// changeTags adds and removes tags on one or many todos, all of them
+// are changed in a single transaction
+func changeTags(opts Opts, repo Repository) error {
+	listMapping := repo.GetListMapping()
+
+	ids := []string{}
+	for _, index := range opts.params["ids"].([]string) {
+		id, ok := listMapping[index]
+		if !ok {
+			return fmt.Errorf("No todo listed as %s", index)
+		}
+		ids = append(ids, id)
+	}
+	ids = uniqueIDs(ids)
+
+	err := repo.MutateTodos(UserKey, ids, func(todo *Todo) error {
+		applyTagChanges(todo, opts)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Tags of %d todos updated\n", len(ids))
+	return nil
+}
+
+// renameTag replaces the tag on every todo having it. Renaming to a tag
+// in use is refused, merging needs both tags to be in use. The store
+// checks the tags and rewrites the todos and the tag index in a single
+// transaction
+func renameTag(opts Opts, repo Repository) error {
+	from := opts.params["from"].(string)
+	to := opts.params["to"].(string)
+	merge := opts.params["merge"].(bool)
+
+	if from == to {
+		return fmt.Errorf("Tags #%s and #%s are the same", from, to)
+	}
+
+	count, err := repo.ReplaceTag(UserKey, from, to, merge)
+	if err != nil {
+		return err
+	}
+
+	if merge {
+		fmt.Printf("Tag #%s merged into #%s on %d todos\n", from, to, count)
+	} else {
+		fmt.Printf("Tag #%s renamed to #%s on %d todos\n", from, to, count)
+	}
+	return nil
+}
+
+// checkReplaceTag tells if the tag on tagged todos can be replaced by
+// the one already on existing todos, stores call it inside the
+// transaction of ReplaceTag
+func checkReplaceTag(from string, to string, merge bool, tagged int, existing int) error {
+	if tagged == 0 {
+		return fmt.Errorf("No todos are tagged #%s", from)
+	}
+	if merge && existing == 0 {
+		return fmt.Errorf("No todos are tagged #%s, use tag rename", to)
+	}
+	if !merge && existing > 0 {
+		return fmt.Errorf("Tag #%s is already on %d todos, use tag merge", to, existing)
+	}
+	return nil
+}
+
+// replaceTag puts to in place of from in the tags of the todo, keeping
+// their order and dropping the duplicate a merge can leave
+func (t *Todo) replaceTag(from string, to string) {
+	if !hasTag(t.Tags, from) {
+		return
+	}
+
+	tags := []string{}
+	for _, tag := range t.Tags {
+		if tag == from {
+			tag = to
+		}
+		if !hasTag(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	t.Tags = tags
+}
+
+// getTagOpts reads todo tag rename <old> <new> and todo tag merge <from>
+// <into>, the tags can be given with or without #
+func getTagOpts(args []string) (Opts, error) {
+	if len(args) != 3 || (args[0] != "rename" && args[0] != "merge") {
+		return Opts{}, fmt.Errorf("Usage: todo tag rename <old> <new> | todo tag merge <from> <into>")
+	}
+
+	from := strings.TrimPrefix(args[1], "#")
+	to := strings.TrimPrefix(args[2], "#")
+	if from == "" || to == "" || strings.ContainsAny(from+to, ",#") {
+		return Opts{}, fmt.Errorf("Invalid tags %s and %s", args[1], args[2])
+	}
+
+	var opts Opts
+	opts.option = RenameTag
+	opts.params = map[string]interface{}{
+		"from":  from,
+		"to":    to,
+		"merge": args[0] == "merge",
+	}
+	return opts, nil
+}
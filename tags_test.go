@@ -0,0 +1,130 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTodosByTags(t *testing.T) {
+	tests := []struct {
+		query [][]string
+		want  []string
+	}{
+		{[][]string{{"work"}}, []string{"Fix the build", "Write the report"}},
+		{[][]string{{"work", "home"}}, []string{"Fix the build", "Paint the fence", "Write the report"}},
+		{[][]string{{"work"}, {"urgent"}}, []string{"Fix the build"}},
+		{[][]string{{"work", "home"}, {"urgent"}}, []string{"Fix the build", "Paint the fence"}},
+		{[][]string{{"home"}, {"work"}}, []string{}},
+		{[][]string{{"unused"}}, []string{}},
+	}
+
+	for name, repo := range testStores(t) {
+		createTodos(t, repo,
+			newTestTodo("Fix the build", today(), "work", "urgent"),
+			newTestTodo("Write the report", today(), "work"),
+			newTestTodo("Paint the fence", today(), "home", "urgent"),
+			newTestTodo("Read a book", today()),
+		)
+
+		for _, test := range tests {
+			todos, err := todosByTags(test.query, repo)
+			if err != nil {
+				t.Fatalf("%s: todosByTags(%v): %v", name, test.query, err)
+			}
+			if got := titlesOf(todos); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("%s: todosByTags(%v) = %v, want %v", name, test.query, got, test.want)
+			}
+		}
+	}
+}
+
+func TestRenameTag(t *testing.T) {
+	tests := []struct {
+		name  string
+		from  string
+		to    string
+		merge bool
+		fails bool
+		want  map[string][]string
+	}{
+		{"rename", "urgent", "asap", false, false, map[string][]string{
+			"Fix the build":    {"work", "asap"},
+			"Write the report": {"work"},
+			"Paint the fence":  {"home", "asap"},
+		}},
+		{"merge", "home", "work", true, false, map[string][]string{
+			"Fix the build":    {"work", "urgent"},
+			"Write the report": {"work"},
+			"Paint the fence":  {"work", "urgent"},
+		}},
+		{"merge into a tag on the same todo", "urgent", "work", true, false, map[string][]string{
+			"Fix the build":    {"work"},
+			"Write the report": {"work"},
+			"Paint the fence":  {"home", "work"},
+		}},
+		{"rename to a tag in use", "home", "work", false, true, nil},
+		{"merge into an unused tag", "home", "later", true, true, nil},
+		{"rename an unused tag", "later", "soon", false, true, nil},
+		{"rename to itself", "work", "work", false, true, nil},
+	}
+
+	for _, test := range tests {
+		for name, repo := range testStores(t) {
+			todos := []Todo{
+				newTestTodo("Fix the build", today(), "work", "urgent"),
+				newTestTodo("Write the report", today(), "work"),
+				newTestTodo("Paint the fence", today(), "home", "urgent"),
+			}
+			createTodos(t, repo, todos...)
+
+			opts := Opts{option: RenameTag, params: map[string]interface{}{
+				"from": test.from, "to": test.to, "merge": test.merge,
+			}}
+			err := renameTag(opts, repo)
+			if test.fails {
+				if err == nil {
+					t.Errorf("%s: %s: renameTag succeeded, want an error", name, test.name)
+				}
+				continue
+			}
+			if err != nil {
+				t.Fatalf("%s: %s: renameTag: %v", name, test.name, err)
+			}
+
+			for _, todo := range todos {
+				saved, err := repo.GetTodo(UserKey, todo.ID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(saved.Tags, test.want[todo.Title]) {
+					t.Errorf("%s: %s: tags of %s = %v, want %v", name, test.name, todo.Title, saved.Tags, test.want[todo.Title])
+				}
+			}
+
+			tagged, err := repo.GetTodosByTag(UserKey, test.from)
+			if err != nil || len(tagged) != 0 {
+				t.Errorf("%s: %s: %d todos still tagged %s, %v", name, test.name, len(tagged), test.from, err)
+			}
+		}
+	}
+}
+
+func TestChangeTagsOnRepeatedTodo(t *testing.T) {
+	for name, repo := range testStores(t) {
+		todo := newTestTodo("Fix the build", today(), "work")
+		createTodos(t, repo, todo)
+
+		err := repo.MutateTodos(UserKey, []string{todo.ID, todo.ID}, func(changed *Todo) error {
+			changed.Tags = append(changed.Tags, "urgent")
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("%s: MutateTodos: %v", name, err)
+		}
+
+		saved, _ := repo.GetTodo(UserKey, todo.ID)
+		if want := []string{"work", "urgent"}; !reflect.DeepEqual(saved.Tags, want) {
+			t.Errorf("%s: tags = %v, want %v", name, saved.Tags, want)
+		}
+	}
+}
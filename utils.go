@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"runtime"
+	"strings"
 	"time"
 )
 
@@ -21,33 +22,103 @@ func tomorrow() time.Time {
 	return startOfDay(time.Now().Add(24 * time.Hour))
 }
 
-func toDate(str string) time.Time {
+// startOfWeek returns the first day of the week containing t, the
+// week starting on the configured weekday
+func startOfWeek(t time.Time) time.Time {
+	t = startOfDay(t)
+	offset := (int(t.Weekday()) - int(config.WeekStart) + 7) % 7
+	return t.AddDate(0, 0, -offset)
+}
+
+func toWeek(str string) time.Time {
 	switch str {
-	case "today":
-		return today()
-	case "yesterday":
-		return startOfDay(time.Now().Add(-1 * 24 * time.Hour))
-	case "tomorrow":
-		return startOfDay(time.Now().Add(24 * time.Hour))
+	case "lastweek":
+		return startOfWeek(today()).AddDate(0, 0, -7)
+	case "nextweek":
+		return startOfWeek(today()).AddDate(0, 0, 7)
 	default:
-		zone, _ := time.Now().Zone()
-		parsed, err := time.Parse("2006-01-02 MST", str+" "+zone)
-		if err != nil {
-			panic("unknown value for toDate " + str)
-		}
-		return startOfDay(parsed)
+		return startOfWeek(today())
 	}
 }
 
-func printTodo(todo Todo) {
+// printTodo prints the details of a todo, the hours spent are those of
+// its logged sessions including a running one
+func printTodo(todo Todo, blockers []Todo, sessions []Session) {
+	running := ""
+	if len(sessions) > 0 && sessions[len(sessions)-1].running() {
+		running = ", timer running"
+	}
+
 	fmt.Printf(`
-Task   : %s
-Due    : %s
-Done   : %v
-Effort : %.1f hours
-Tags   : %s
+Task     : %s
+Due      : %s
+Done     : %v
+Effort   : %.1f hours estimated, %.1f hours spent
+Tags     : %s
+Priority : %s
+`, todo.Title, todo.datestr(), todo.Done, todo.Estimate, spentHours(sessions), todo.tagsstr(), todo.Priority)
+
+	if len(sessions) > 0 {
+		fmt.Printf("Sessions : %d logged%s\n", len(sessions), running)
+	}
+
+	if !todo.Created.IsZero() {
+		fmt.Printf("Created  : %s\n", todo.Created.Local().Format("2 Jan 2006 15:04"))
+	}
+
+	if !todo.Modified.IsZero() {
+		fmt.Printf("Modified : %s\n", todo.Modified.Local().Format("2 Jan 2006 15:04"))
+	}
+
+	if !todo.Completed.IsZero() {
+		fmt.Printf("Finished : %s\n", todo.Completed.Local().Format("2 Jan 2006 15:04"))
+	}
+
+	if todo.Recur != "" {
+		fmt.Printf("Repeats  : %s\n", todo.Recur)
+	}
+
+	if len(todo.Contexts) > 0 {
+		fmt.Printf("Contexts : %s\n", todo.contextsstr())
+	}
 
-`, todo.Title, todo.datestr(), todo.Done, todo.Effort, todo.tagsstr())
+	if todo.Project != "" {
+		fmt.Printf("Project  : %s\n", todo.Project)
+	}
+
+	if todo.Postponed > 0 {
+		fmt.Printf("Deferred : %d times\n", todo.Postponed)
+	}
+
+	if len(todo.DependsOn) > 0 {
+		if len(blockers) > 0 {
+			fmt.Printf("Blocked  : by %s\n", titles(blockers))
+		} else {
+			fmt.Printf("Blocked  : no, %d dependencies done\n", len(todo.DependsOn))
+		}
+	}
+
+	if len(todo.Subtasks) > 0 {
+		fmt.Printf("Subtasks : %s\n", strings.TrimSpace(todo.progress()))
+		for i, subtask := range todo.Subtasks {
+			if subtask.Done {
+				fmt.Printf("  %d. [X] %s\n", i+1, subtask.Title)
+			} else {
+				fmt.Printf("  %d. [ ] %s\n", i+1, subtask.Title)
+			}
+		}
+	}
+
+	if len(todo.Notes) > 0 {
+		fmt.Println("Notes    :")
+		for _, note := range todo.Notes {
+			fmt.Printf("  %s\n", note.Time.Format("2 Jan 2006 15:04"))
+			for _, line := range strings.Split(note.Text, "\n") {
+				fmt.Printf("    %s\n", line)
+			}
+		}
+	}
+	fmt.Println()
 }
 
 func userHomeDir() string {
@@ -66,16 +137,25 @@ func userHomeDir() string {
 	return os.Getenv("HOME")
 }
 
-func getDbPath() string {
+func getTodoDir() string {
 	homeDir := userHomeDir()
-	dbDir := homeDir + string(os.PathSeparator) + "todo"
-	if _, err := os.Stat(dbDir); os.IsNotExist(err) {
-		err = os.Mkdir(dbDir, 0700)
+	todoDir := homeDir + string(os.PathSeparator) + "todo"
+	if _, err := os.Stat(todoDir); os.IsNotExist(err) {
+		err = os.Mkdir(todoDir, 0700)
 		if err != nil {
 			log.Fatal(err)
 		}
 	}
 
-	dbFile := dbDir + string(os.PathSeparator) + "todo.db"
+	return todoDir
+}
+
+func getDbPath() string {
+	dbFile := getTodoDir() + string(os.PathSeparator) + "todo.db"
 	return dbFile
 }
+
+func getConfigPath() string {
+	configFile := getTodoDir() + string(os.PathSeparator) + "config"
+	return configFile
+}